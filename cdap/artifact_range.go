@@ -0,0 +1,187 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cdap
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// artifactRange is a range of artifact versions, such as the parents of a
+// plugin artifact.
+type artifactRange struct {
+	scope          string
+	name           string
+	lower          string
+	upper          string
+	lowerInclusive bool
+	upperInclusive bool
+}
+
+// artifactParents are the parents of an artifact in the form used by the
+// Artifact-Extends header. In the JSON config, each parent is either a range
+// string such as system:cdap-data-pipeline[6.0.0,7.0.0) or an object such as
+// {"name": "cdap-data-pipeline", "scope": "system", "range": "[6.0.0,7.0.0)"}.
+type artifactParents []string
+
+type artifactParentObject struct {
+	Name  string `json:"name"`
+	Scope string `json:"scope"`
+	Range string `json:"range"`
+}
+
+func (p *artifactParents) UnmarshalJSON(b []byte) error {
+	var raw []json.RawMessage
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return err
+	}
+
+	parents := make(artifactParents, 0, len(raw))
+	for i, r := range raw {
+		var s string
+		if err := json.Unmarshal(r, &s); err == nil {
+			parents = append(parents, s)
+			continue
+		}
+
+		dec := json.NewDecoder(bytes.NewReader(r))
+		dec.DisallowUnknownFields()
+		var o artifactParentObject
+		if err := dec.Decode(&o); err != nil {
+			return fmt.Errorf("parents[%d] must be a string or an object with name, scope and range: %v", i, err)
+		}
+		if o.Name == "" || o.Range == "" {
+			return fmt.Errorf("parents[%d] must set name and range", i)
+		}
+		s = o.Name + o.Range
+		if o.Scope != "" {
+			s = strings.ToLower(o.Scope) + ":" + s
+		}
+		parents = append(parents, s)
+	}
+	*p = parents
+	return nil
+}
+
+// artifactRangeNameRE matches an optionally scoped artifact name.
+var artifactRangeNameRE = regexp.MustCompile(`^((system|user):)?[\w.-]+$`)
+
+// parseArtifactRange parses a CDAP artifact range such as
+// system:cdap-data-pipeline[6.0.0,7.0.0). The scope is optional, "[" and "]"
+// mark inclusive bounds and "(" and ")" exclusive ones.
+func parseArtifactRange(s string) (*artifactRange, error) {
+	start := strings.IndexAny(s, "[(")
+	if start < 0 {
+		return nil, errors.New("missing version range, want the form scope:name[lower,upper)")
+	}
+
+	name := s[:start]
+	if !artifactRangeNameRE.MatchString(strings.ToLower(name)) {
+		return nil, fmt.Errorf("invalid artifact name %q", name)
+	}
+	r := &artifactRange{name: name}
+	if parts := strings.SplitN(name, ":", 2); len(parts) == 2 {
+		r.scope, r.name = strings.ToLower(parts[0]), parts[1]
+	}
+
+	versions := s[start:]
+	end := versions[len(versions)-1]
+	if end != ']' && end != ')' {
+		return nil, errors.New(`version range must end with "]" or ")"`)
+	}
+	r.lowerInclusive = versions[0] == '['
+	r.upperInclusive = end == ']'
+
+	bounds := strings.Split(versions[1:len(versions)-1], ",")
+	if len(bounds) != 2 {
+		return nil, errors.New("version range must have exactly one lower and one upper bound")
+	}
+	r.lower, r.upper = strings.TrimSpace(bounds[0]), strings.TrimSpace(bounds[1])
+	if r.lower == "" || r.upper == "" {
+		return nil, errors.New("version range bounds must not be empty")
+	}
+
+	switch c := compareVersions(r.lower, r.upper); {
+	case c > 0:
+		return nil, fmt.Errorf("lower bound %q is greater than upper bound %q", r.lower, r.upper)
+	case c == 0 && !(r.lowerInclusive && r.upperInclusive):
+		return nil, fmt.Errorf("version range with equal bounds %q must be inclusive on both ends", r.lower)
+	}
+	return r, nil
+}
+
+// contains reports whether the version is within the range.
+func (r *artifactRange) contains(version string) bool {
+	lower, upper := compareVersions(version, r.lower), compareVersions(version, r.upper)
+	return (lower > 0 || (lower == 0 && r.lowerInclusive)) && (upper < 0 || (upper == 0 && r.upperInclusive))
+}
+
+// compareVersions compares two artifact versions of the form
+// major.minor.fix[-suffix] and returns -1, 0 or 1. Versions with a suffix,
+// such as SNAPSHOT versions, are smaller than the same version without one.
+func compareVersions(a, b string) int {
+	aNums, aSuffix := splitVersion(a)
+	bNums, bSuffix := splitVersion(b)
+	for i := 0; i < len(aNums) || i < len(bNums); i++ {
+		var x, y int
+		if i < len(aNums) {
+			x = aNums[i]
+		}
+		if i < len(bNums) {
+			y = bNums[i]
+		}
+		if x != y {
+			if x < y {
+				return -1
+			}
+			return 1
+		}
+	}
+
+	switch {
+	case aSuffix == bSuffix:
+		return 0
+	case aSuffix == "":
+		return 1
+	case bSuffix == "":
+		return -1
+	case aSuffix < bSuffix:
+		return -1
+	default:
+		return 1
+	}
+}
+
+func splitVersion(v string) ([]int, string) {
+	var suffix string
+	if i := strings.IndexAny(v, "-_"); i >= 0 {
+		v, suffix = v[:i], v[i+1:]
+	}
+	var nums []int
+	for _, p := range strings.Split(v, ".") {
+		n, err := strconv.Atoi(p)
+		if err != nil {
+			// Treat the rest of the version as a suffix, such as in 1.0.0.RELEASE.
+			break
+		}
+		nums = append(nums, n)
+	}
+	return nums, suffix
+}
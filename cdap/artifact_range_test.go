@@ -0,0 +1,143 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cdap
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestParseArtifactRange(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    *artifactRange
+		wantErr bool
+	}{
+		{
+			in:   "system:cdap-data-pipeline[6.0.0,7.0.0)",
+			want: &artifactRange{scope: "system", name: "cdap-data-pipeline", lower: "6.0.0", upper: "7.0.0", lowerInclusive: true},
+		},
+		{
+			in:   "cdap-data-pipeline(6.0.0,7.0.0]",
+			want: &artifactRange{name: "cdap-data-pipeline", lower: "6.0.0", upper: "7.0.0", upperInclusive: true},
+		},
+		{
+			in:   "USER:plugins[1.0.0, 1.0.0]",
+			want: &artifactRange{scope: "user", name: "plugins", lower: "1.0.0", upper: "1.0.0", lowerInclusive: true, upperInclusive: true},
+		},
+		{
+			in:   "plugins(1.0.0,2.0.0)",
+			want: &artifactRange{name: "plugins", lower: "1.0.0", upper: "2.0.0"},
+		},
+		// CDAP requires both bounds, so unbounded ranges are rejected.
+		{in: "plugins[1.0.0,)", wantErr: true},
+		{in: "plugins(,2.0.0)", wantErr: true},
+		{in: "plugins[,]", wantErr: true},
+		{in: "plugins", wantErr: true},
+		{in: "plugins[1.0.0,2.0.0", wantErr: true},
+		{in: "plugins[1.0.0]", wantErr: true},
+		{in: "plugins[2.0.0,1.0.0)", wantErr: true},
+		{in: "plugins[1.0.0,1.0.0)", wantErr: true},
+		{in: "other:plugins[1.0.0,2.0.0)", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := parseArtifactRange(tt.in)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("parseArtifactRange(%q) = %+v, want error", tt.in, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("parseArtifactRange(%q) returned error: %v", tt.in, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("parseArtifactRange(%q) = %+v, want %+v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestArtifactRangeContains(t *testing.T) {
+	tests := []struct {
+		rng     string
+		version string
+		want    bool
+	}{
+		{"p[1.0.0,2.0.0)", "1.0.0", true},
+		{"p(1.0.0,2.0.0)", "1.0.0", false},
+		{"p[1.0.0,2.0.0)", "1.5.3", true},
+		{"p[1.0.0,2.0.0)", "2.0.0", false},
+		{"p[1.0.0,2.0.0]", "2.0.0", true},
+		{"p[1.0.0,2.0.0]", "2.0.1", false},
+		{"p[1.0.0,2.0.0)", "0.9.9", false},
+		{"p[1.0.0,2.0.0)", "2.0.0-SNAPSHOT", true},
+		{"p[1.0.0,1.0.0]", "1.0.0", true},
+	}
+	for _, tt := range tests {
+		r, err := parseArtifactRange(tt.rng)
+		if err != nil {
+			t.Fatalf("parseArtifactRange(%q) returned error: %v", tt.rng, err)
+		}
+		if got := r.contains(tt.version); got != tt.want {
+			t.Errorf("%s contains %q = %v, want %v", tt.rng, tt.version, got, tt.want)
+		}
+	}
+}
+
+func TestArtifactParentsUnmarshalJSON(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    artifactParents
+		wantErr bool
+	}{
+		{
+			in:   `["system:cdap-data-pipeline[6.0.0,7.0.0)", "plugins[1.0.0,2.0.0)"]`,
+			want: artifactParents{"system:cdap-data-pipeline[6.0.0,7.0.0)", "plugins[1.0.0,2.0.0)"},
+		},
+		{
+			in:   `[{"name": "cdap-data-pipeline", "scope": "SYSTEM", "range": "[6.0.0,7.0.0)"}, {"name": "plugins", "range": "[1.0.0,2.0.0)"}]`,
+			want: artifactParents{"system:cdap-data-pipeline[6.0.0,7.0.0)", "plugins[1.0.0,2.0.0)"},
+		},
+		{
+			in:   `["system:cdap-data-pipeline[6.0.0,7.0.0)", {"name": "cdap-data-streams", "scope": "system", "range": "[6.0.0,7.0.0)"}]`,
+			want: artifactParents{"system:cdap-data-pipeline[6.0.0,7.0.0)", "system:cdap-data-streams[6.0.0,7.0.0)"},
+		},
+		{in: `[]`, want: artifactParents{}},
+		{in: `"system:cdap-data-pipeline[6.0.0,7.0.0)"`, wantErr: true},
+		{in: `[1]`, wantErr: true},
+		{in: `[{"name": "plugins"}]`, wantErr: true},
+		{in: `[{"range": "[1.0.0,2.0.0)"}]`, wantErr: true},
+		{in: `[{"name": "plugins", "range": "[1.0.0,2.0.0)", "version": "1.0.0"}]`, wantErr: true},
+	}
+	for _, tt := range tests {
+		var got artifactParents
+		err := json.Unmarshal([]byte(tt.in), &got)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("unmarshal of %s = %q, want error", tt.in, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("unmarshal of %s returned error: %v", tt.in, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("unmarshal of %s = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,142 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cdap
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/structure"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+)
+
+// dataSourceAppConfig shows what an application or program runs with: the
+// config it was deployed with and the preferences it inherits, along with the
+// level each preference comes from.
+// https://docs.cdap.io/cdap/current/en/reference-manual/http-restful-api/preferences.html
+func dataSourceAppConfig() *schema.Resource {
+	return &schema.Resource{
+		Read: dataSourceAppConfigRead,
+
+		Schema: map[string]*schema.Schema{
+			"namespace": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Computed:    true,
+				Description: "The name of the namespace in which the application belongs. If not provided, the provider's default_namespace is used.",
+			},
+			"application": {
+				Type:        schema.TypeString,
+				Required:    true,
+				Description: "The name of the application.",
+			},
+			"program_type": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				RequiredWith: []string{"program_name"},
+				ValidateFunc: validation.StringInSlice([]string{"workflows", "services", "spark", "mapreduce", "workers"}, false),
+				Description:  "The type of the program, one of workflows, services, spark, mapreduce or workers. If set, the preferences of the program are included.",
+			},
+			"program_name": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				RequiredWith: []string{"program_type"},
+				Description:  "The name of the program.",
+			},
+			"config": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "The config the application was deployed with as JSON. Empty if it has none.",
+			},
+			"preferences": {
+				Type:        schema.TypeMap,
+				Computed:    true,
+				Description: "The effective preferences, with the ones of more specific levels taking precedence over the ones of the instance, namespace and application.",
+				Elem: &schema.Schema{
+					Type: schema.TypeString,
+				},
+			},
+			"preference_sources": {
+				Type:        schema.TypeMap,
+				Computed:    true,
+				Description: "The level each effective preference is set on, one of instance, namespace, application or program.",
+				Elem: &schema.Schema{
+					Type: schema.TypeString,
+				},
+			},
+		},
+	}
+}
+
+func dataSourceAppConfigRead(d *schema.ResourceData, m interface{}) error {
+	config := m.(*Config)
+	if err := setDefaultNamespace(d, config); err != nil {
+		return err
+	}
+	namespace := d.Get("namespace").(string)
+	app := d.Get("application").(string)
+	appAddr := urlJoin(config.host, "/v3/namespaces", namespace, "/apps", app)
+
+	detail := new(appDetail)
+	err := getJSON(config, appAddr, detail)
+	if isNotFound(err) {
+		return fmt.Errorf("application %q not found in namespace %q", app, namespace)
+	}
+	if err != nil {
+		return err
+	}
+	conf := ""
+	if detail.Configuration != "" {
+		if conf, err = structure.NormalizeJsonString(detail.Configuration); err != nil {
+			return fmt.Errorf("failed to parse configuration of application %q: %v", app, err)
+		}
+	}
+
+	// The levels in increasing order of precedence.
+	levels := []struct{ name, addr string }{
+		{"instance", urlJoin(config.host, "/v3/preferences")},
+		{"namespace", urlJoin(config.host, "/v3/namespaces", namespace, "/preferences")},
+		{"application", urlJoin(appAddr, "/preferences")},
+	}
+	id := []string{namespace, app}
+	if pType, ok := d.GetOk("program_type"); ok {
+		pName := d.Get("program_name").(string)
+		levels = append(levels, struct{ name, addr string }{"program", urlJoin(appAddr, pType.(string), pName, "/preferences")})
+		id = append(id, pType.(string), pName)
+	}
+
+	prefs := make(map[string]string)
+	sources := make(map[string]string)
+	for _, l := range levels {
+		p, err := getPreferences(config, l.addr)
+		if isNotFound(err) {
+			return fmt.Errorf("%s preferences for application %q not found, check that the %s exists: %v", l.name, app, l.name, err)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read %s preferences: %v", l.name, err)
+		}
+		for k, v := range p {
+			prefs[k] = v
+			sources[k] = l.name
+		}
+	}
+
+	d.Set("config", conf)
+	d.Set("preferences", prefs)
+	d.Set("preference_sources", sources)
+	d.SetId(strings.Join(id, "/"))
+	return nil
+}
@@ -0,0 +1,177 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cdap
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+// dataSourceApplication looks up an application deployed outside of this
+// config, for example to start one of its programs with cdap_program_run.
+// https://docs.cdap.io/cdap/current/en/reference-manual/http-restful-api/lifecycle.html#details-of-a-deployed-application
+func dataSourceApplication() *schema.Resource {
+	return &schema.Resource{
+		Read: dataSourceApplicationRead,
+
+		Schema: map[string]*schema.Schema{
+			"name": {
+				Type:        schema.TypeString,
+				Required:    true,
+				Description: "The name of the application.",
+			},
+			"namespace": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Computed:    true,
+				Description: "The name of the namespace in which the application belongs. If not provided, the provider's default_namespace is used.",
+			},
+			"app_version": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "The version of the application.",
+			},
+			"description": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "The description of the application.",
+			},
+			"config": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "The JSON config the application was deployed with.",
+			},
+			"artifact": {
+				Type:        schema.TypeList,
+				Computed:    true,
+				Description: "The artifact the application was deployed from.",
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"name": {
+							Type:        schema.TypeString,
+							Computed:    true,
+							Description: "The name of the artifact.",
+						},
+						"version": {
+							Type:        schema.TypeString,
+							Computed:    true,
+							Description: "The version of the artifact.",
+						},
+						"scope": {
+							Type:        schema.TypeString,
+							Computed:    true,
+							Description: "The scope of the artifact, either user or system.",
+						},
+					},
+				},
+			},
+			"programs": {
+				Type:        schema.TypeList,
+				Computed:    true,
+				Description: "The programs of the application.",
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"name": {
+							Type:        schema.TypeString,
+							Computed:    true,
+							Description: "The name of the program.",
+						},
+						"type": {
+							Type:        schema.TypeString,
+							Computed:    true,
+							Description: "The type of the program, one of mapreduce, services, spark, workers, or workflows. Services and workers can be kept running with cdap_program_run.",
+						},
+						"description": {
+							Type:        schema.TypeString,
+							Computed:    true,
+							Description: "The description of the program.",
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+// programTypePaths maps the program types reported by CDAP to the ones used in program URLs.
+var programTypePaths = map[string]string{
+	"mapreduce": "mapreduce",
+	"service":   "services",
+	"spark":     "spark",
+	"worker":    "workers",
+	"workflow":  "workflows",
+}
+
+func dataSourceApplicationRead(d *schema.ResourceData, m interface{}) error {
+	config := m.(*Config)
+	if err := setDefaultNamespace(d, config); err != nil {
+		return err
+	}
+	name := d.Get("name").(string)
+	namespace := d.Get("namespace").(string)
+	addr := urlJoin(config.host, "/v3/namespaces", namespace, "/apps", name)
+
+	req, err := http.NewRequest(http.MethodGet, addr, nil)
+	if err != nil {
+		return err
+	}
+
+	b, err := httpCall(config.httpClient, req)
+	if isNotFound(err) {
+		return fmt.Errorf("application %q not found in namespace %q", name, namespace)
+	}
+	if err != nil {
+		return err
+	}
+
+	detail := new(appDetail)
+	if err := json.Unmarshal(b, detail); err != nil {
+		return err
+	}
+
+	var artifact []map[string]interface{}
+	if detail.Artifact != nil {
+		artifact = append(artifact, map[string]interface{}{
+			"name":    detail.Artifact.Name,
+			"version": detail.Artifact.Version,
+			"scope":   strings.ToLower(detail.Artifact.Scope),
+		})
+	}
+
+	var programs []map[string]interface{}
+	for _, p := range detail.Programs {
+		typ, ok := programTypePaths[strings.ToLower(p.Type)]
+		if !ok {
+			typ = strings.ToLower(p.Type)
+		}
+		programs = append(programs, map[string]interface{}{
+			"name":        p.Name,
+			"type":        typ,
+			"description": p.Description,
+		})
+	}
+
+	d.Set("app_version", detail.AppVersion)
+	d.Set("description", detail.Description)
+	d.Set("config", detail.Configuration)
+	d.Set("artifact", artifact)
+	d.Set("programs", programs)
+	d.SetId(namespace + "/" + name)
+	return nil
+}
@@ -0,0 +1,199 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cdap
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+)
+
+// dataSourceArtifact looks up an existing artifact, such as one uploaded by
+// another team, without managing it.
+// https://docs.cdap.io/cdap/current/en/reference-manual/http-restful-api/artifact.html
+func dataSourceArtifact() *schema.Resource {
+	return &schema.Resource{
+		Read: dataSourceArtifactRead,
+
+		Schema: map[string]*schema.Schema{
+			"name": {
+				Type:        schema.TypeString,
+				Required:    true,
+				Description: "The name of the artifact.",
+			},
+			"namespace": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Computed:    true,
+				Description: "The name of the namespace in which the artifact belongs. If not provided, the provider's default_namespace is used.",
+			},
+			"versions": {
+				Type:        schema.TypeList,
+				Computed:    true,
+				Description: "The available versions of the artifact, from oldest to latest.",
+				Elem: &schema.Schema{
+					Type: schema.TypeString,
+				},
+			},
+			"latest_version": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "The latest version of the artifact.",
+			},
+			"scope": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				Computed:     true,
+				ValidateFunc: validation.StringInSlice([]string{"user", "system"}, false),
+				Description:  "The scope of the artifact, either user or system. If not provided, the user scope is looked up first, then the system scope, so system artifacts such as cdap-data-pipeline are found too.",
+			},
+			"plugin_classes": pluginClassesSchema("The plugin classes contained in the latest version of the artifact."),
+		},
+	}
+}
+
+// pluginClassesSchema is the computed list of plugin classes of an artifact.
+func pluginClassesSchema(description string) *schema.Schema {
+	return &schema.Schema{
+		Type:        schema.TypeList,
+		Computed:    true,
+		Description: description,
+		Elem: &schema.Resource{
+			Schema: map[string]*schema.Schema{
+				"name": {
+					Type:        schema.TypeString,
+					Computed:    true,
+					Description: "The name of the plugin.",
+				},
+				"type": {
+					Type:        schema.TypeString,
+					Computed:    true,
+					Description: "The type of the plugin.",
+				},
+				"class_name": {
+					Type:        schema.TypeString,
+					Computed:    true,
+					Description: "The fully qualified class name of the plugin.",
+				},
+				"description": {
+					Type:        schema.TypeString,
+					Computed:    true,
+					Description: "The description of the plugin.",
+				},
+			},
+		},
+	}
+}
+
+func flattenPluginClasses(classes []*pluginClass) []map[string]interface{} {
+	plugins := make([]map[string]interface{}, 0, len(classes))
+	for _, p := range classes {
+		plugins = append(plugins, map[string]interface{}{
+			"name":        p.Name,
+			"type":        p.Type,
+			"class_name":  p.ClassName,
+			"description": p.Description,
+		})
+	}
+	return plugins
+}
+
+// artifactSummary is an entry returned by the CDAP artifact list endpoints.
+type artifactSummary struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+	Scope   string `json:"scope"`
+}
+
+func dataSourceArtifactRead(d *schema.ResourceData, m interface{}) error {
+	config := m.(*Config)
+	if err := setDefaultNamespace(d, config); err != nil {
+		return err
+	}
+	name := d.Get("name").(string)
+	namespace := d.Get("namespace").(string)
+
+	scopes := []string{"user", "system"}
+	if v, ok := d.GetOk("scope"); ok {
+		scopes = []string{v.(string)}
+	}
+	var summaries []*artifactSummary
+	for _, scope := range scopes {
+		s, err := listArtifactVersions(config, namespace, name, scope)
+		if err != nil && !isNotFound(err) {
+			return err
+		}
+		if len(s) > 0 {
+			summaries = s
+			break
+		}
+	}
+	if len(summaries) == 0 {
+		return fmt.Errorf("artifact %q not found in namespace %q", name, namespace)
+	}
+
+	// CDAP does not guarantee the order of the versions.
+	sort.SliceStable(summaries, func(i, j int) bool {
+		return compareVersions(summaries[i].Version, summaries[j].Version) < 0
+	})
+	var versions []string
+	for _, s := range summaries {
+		versions = append(versions, s.Version)
+	}
+	latest := summaries[len(summaries)-1]
+
+	ad, err := getArtifactDetail(config, namespace, name, latest.Version, latest.Scope)
+	if err != nil {
+		return fmt.Errorf("failed to get detail of artifact %q version %q: %v", name, latest.Version, err)
+	}
+
+	d.Set("versions", versions)
+	d.Set("latest_version", latest.Version)
+	d.Set("scope", strings.ToLower(latest.Scope))
+	d.Set("plugin_classes", flattenPluginClasses(ad.Classes.Plugins))
+	d.SetId(namespace + "/" + name)
+	return nil
+}
+
+// listArtifactVersions lists all versions of the artifact with the given name.
+// If scope is empty, CDAP lists the versions in the user scope.
+func listArtifactVersions(config *Config, namespace, name, scope string) ([]*artifactSummary, error) {
+	addr := urlJoin(config.host, "/v3/namespaces", namespace, "/artifacts", name)
+	if scope != "" {
+		addr += "?scope=" + url.QueryEscape(strings.ToUpper(scope))
+	}
+
+	req, err := http.NewRequest(http.MethodGet, addr, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	b, err := httpCall(config.httpClient, req)
+	if err != nil {
+		return nil, err
+	}
+
+	var summaries []*artifactSummary
+	if err := json.Unmarshal(b, &summaries); err != nil {
+		return nil, err
+	}
+	return summaries, nil
+}
@@ -0,0 +1,146 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cdap
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+// dataSourceArtifactConfig validates an artifact's JSON config and JAR
+// without uploading anything, so CI can check configs before they are applied.
+func dataSourceArtifactConfig() *schema.Resource {
+	return &schema.Resource{
+		Read: dataSourceArtifactConfigRead,
+
+		Schema: map[string]*schema.Schema{
+			"json_config_path": {
+				Type:        schema.TypeString,
+				Required:    true,
+				Description: "The local path to the JSON config of the artifact.",
+			},
+			"jar_binary_path": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				RequiredWith: []string{"version"},
+				Description:  "The local path to the JAR binary of the artifact. If set, its manifest version is checked against version.",
+			},
+			"version": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Description: "The version of the artifact, checked against the JAR manifest.",
+			},
+			"resolve_parents": {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Default:     false,
+				Description: "Whether to check that every parent range matches at least one artifact on the instance. Otherwise the instance is not contacted.",
+			},
+			"namespace": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Computed:    true,
+				Description: "The namespace to resolve user scoped parents in. If not provided, the provider's default_namespace is used.",
+			},
+			"parents": {
+				Type:        schema.TypeList,
+				Computed:    true,
+				Description: "The parents of the artifact as artifact ranges.",
+				Elem: &schema.Schema{
+					Type: schema.TypeString,
+				},
+			},
+			"properties": {
+				Type:        schema.TypeMap,
+				Computed:    true,
+				Description: "The properties in the JSON config.",
+				Elem: &schema.Schema{
+					Type: schema.TypeString,
+				},
+			},
+		},
+	}
+}
+
+func dataSourceArtifactConfigRead(d *schema.ResourceData, m interface{}) error {
+	config := m.(*Config)
+	if err := setDefaultNamespace(d, config); err != nil {
+		return err
+	}
+	path := localPath(config, d.Get("json_config_path").(string))
+
+	conf, err := readArtifactConfig(path)
+	if err != nil {
+		return err
+	}
+
+	if jarPath, ok := d.GetOk("jar_binary_path"); ok {
+		if err := validateLocalJar(localPath(config, jarPath.(string)), d.Get("version").(string)); err != nil {
+			return err
+		}
+	}
+
+	if d.Get("resolve_parents").(bool) {
+		for _, p := range conf.Parents {
+			if err := resolveArtifactParent(config, d.Get("namespace").(string), p); err != nil {
+				return fmt.Errorf("invalid artifact config %q: %v", path, err)
+			}
+		}
+	}
+
+	d.Set("parents", []string(conf.Parents))
+	d.Set("properties", conf.Properties)
+	d.SetId(path)
+	return nil
+}
+
+func validateLocalJar(path, version string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	return validateArtifactVersion(f, fi.Size(), version)
+}
+
+// resolveArtifactParent returns an error unless an artifact on the instance
+// is within the parent range.
+func resolveArtifactParent(config *Config, namespace, parent string) error {
+	r, err := parseArtifactRange(parent)
+	if err != nil {
+		return err
+	}
+	scope := r.scope
+	if scope == "" {
+		scope = "user"
+	}
+
+	summaries, err := listArtifactVersions(config, namespace, r.name, scope)
+	if err != nil && !isNotFound(err) {
+		return fmt.Errorf("failed to list versions of parent %q: %v", parent, err)
+	}
+	for _, s := range summaries {
+		if r.contains(s.Version) {
+			return nil
+		}
+	}
+	return fmt.Errorf("no %s artifact %q in namespace %q matches parent %q", scope, r.name, namespace, parent)
+}
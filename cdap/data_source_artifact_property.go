@@ -0,0 +1,101 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cdap
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+)
+
+// dataSourceArtifactProperty reads a single property of an artifact, for
+// wiring into other configs without fetching the whole artifact.
+// https://docs.cdap.io/cdap/current/en/reference-manual/http-restful-api/artifact.html#retrieve-artifact-properties
+func dataSourceArtifactProperty() *schema.Resource {
+	return &schema.Resource{
+		Read: dataSourceArtifactPropertyRead,
+
+		Schema: map[string]*schema.Schema{
+			"name": {
+				Type:        schema.TypeString,
+				Required:    true,
+				Description: "The name of the artifact.",
+			},
+			"version": {
+				Type:        schema.TypeString,
+				Required:    true,
+				Description: "The version of the artifact.",
+			},
+			"namespace": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Computed:    true,
+				Description: "The name of the namespace in which the artifact belongs. If not provided, the provider's default_namespace is used.",
+			},
+			"scope": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				Default:      "user",
+				ValidateFunc: validation.StringInSlice([]string{"user", "system"}, false),
+				Description:  "The scope of the artifact, either user or system.",
+			},
+			"key": {
+				Type:        schema.TypeString,
+				Required:    true,
+				Description: "The key of the property.",
+			},
+			"value": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "The value of the property.",
+			},
+		},
+	}
+}
+
+func dataSourceArtifactPropertyRead(d *schema.ResourceData, m interface{}) error {
+	config := m.(*Config)
+	if err := setDefaultNamespace(d, config); err != nil {
+		return err
+	}
+	name := d.Get("name").(string)
+	version := d.Get("version").(string)
+	namespace := d.Get("namespace").(string)
+	key := d.Get("key").(string)
+	addr := urlJoin(config.host, "/v3/namespaces", namespace, "/artifacts", name, "/versions", version, "/properties", url.PathEscape(key))
+	addr += "?scope=" + url.QueryEscape(strings.ToUpper(d.Get("scope").(string)))
+
+	req, err := http.NewRequest(http.MethodGet, addr, nil)
+	if err != nil {
+		return err
+	}
+
+	b, err := httpCall(config.httpClient, req)
+	// CDAP returns an empty body for keys the artifact does not have.
+	if isNotFound(err) || (err == nil && len(b) == 0) {
+		return fmt.Errorf("property %q not found on artifact %q version %q in namespace %q", key, name, version, namespace)
+	}
+	if err != nil {
+		return err
+	}
+
+	d.Set("value", string(b))
+	d.SetId(strings.Join([]string{namespace, name, version, key}, "/"))
+	return nil
+}
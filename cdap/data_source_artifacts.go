@@ -0,0 +1,175 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cdap
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+)
+
+// dataSourceArtifacts lists the artifacts in a namespace, for example to
+// audit them or to import them into cdap_local_artifact resources.
+// https://docs.cdap.io/cdap/current/en/reference-manual/http-restful-api/artifact.html
+func dataSourceArtifacts() *schema.Resource {
+	return &schema.Resource{
+		Read: dataSourceArtifactsRead,
+
+		Schema: map[string]*schema.Schema{
+			"namespace": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Computed:    true,
+				Description: "The name of the namespace to list the artifacts of. If not provided, the provider's default_namespace is used.",
+			},
+			"name_prefix": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Description: "Only list artifacts whose name starts with this prefix.",
+			},
+			"scope": {
+				Type:          schema.TypeString,
+				Optional:      true,
+				ValidateFunc:  validation.StringInSlice([]string{"user", "system"}, false),
+				ConflictsWith: []string{"include_system"},
+				Description:   "Only list artifacts in this scope, either user or system.",
+			},
+			"include_system": {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Default:     false,
+				Description: "Whether to also list system artifacts. By default, only user artifacts are listed.",
+			},
+			"artifacts": {
+				Type:        schema.TypeList,
+				Computed:    true,
+				Description: "The artifacts, with one entry per version.",
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"name": {
+							Type:        schema.TypeString,
+							Computed:    true,
+							Description: "The name of the artifact.",
+						},
+						"version": {
+							Type:        schema.TypeString,
+							Computed:    true,
+							Description: "The version of the artifact.",
+						},
+						"scope": {
+							Type:        schema.TypeString,
+							Computed:    true,
+							Description: "The scope of the artifact, either user or system.",
+						},
+						"import_id": {
+							Type:        schema.TypeString,
+							Computed:    true,
+							Description: "The ID to import the artifact version into a cdap_local_artifact with, of the form namespace/name/version.",
+						},
+					},
+				},
+			},
+			"import_ids": {
+				Type:        schema.TypeList,
+				Computed:    true,
+				Description: "The import IDs of all listed artifacts, in the same order as artifacts.",
+				Elem: &schema.Schema{
+					Type: schema.TypeString,
+				},
+			},
+		},
+	}
+}
+
+func dataSourceArtifactsRead(d *schema.ResourceData, m interface{}) error {
+	config := m.(*Config)
+	if err := setDefaultNamespace(d, config); err != nil {
+		return err
+	}
+	namespace := d.Get("namespace").(string)
+
+	summaries, err := listArtifacts(config, namespace, artifactsScope(d))
+	if err != nil {
+		return err
+	}
+
+	prefix := d.Get("name_prefix").(string)
+	var artifacts []map[string]interface{}
+	var importIDs []string
+	for _, s := range summaries {
+		if !strings.HasPrefix(s.Name, prefix) {
+			continue
+		}
+		// System artifacts are imported from the system namespace.
+		importNamespace := namespace
+		if strings.EqualFold(s.Scope, "system") {
+			importNamespace = systemNamespace
+		}
+		importID := strings.Join([]string{importNamespace, s.Name, s.Version}, "/")
+		artifacts = append(artifacts, map[string]interface{}{
+			"name":      s.Name,
+			"version":   s.Version,
+			"scope":     strings.ToLower(s.Scope),
+			"import_id": importID,
+		})
+		importIDs = append(importIDs, importID)
+	}
+
+	d.Set("artifacts", artifacts)
+	d.Set("import_ids", importIDs)
+	d.SetId(namespace)
+	return nil
+}
+
+// artifactsScope returns the scope to list artifacts in, or an empty string
+// to list artifacts in all scopes.
+func artifactsScope(d *schema.ResourceData) string {
+	if scope := d.Get("scope").(string); scope != "" {
+		return scope
+	}
+	if d.Get("include_system").(bool) {
+		return ""
+	}
+	return "user"
+}
+
+// listArtifacts lists all artifacts in the namespace. If scope is empty,
+// artifacts in all scopes are listed.
+func listArtifacts(config *Config, namespace, scope string) ([]*artifactSummary, error) {
+	addr := urlJoin(config.host, "/v3/namespaces", namespace, "/artifacts")
+	if scope != "" {
+		addr += "?scope=" + url.QueryEscape(strings.ToUpper(scope))
+	}
+
+	req, err := http.NewRequest(http.MethodGet, addr, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	b, err := httpCall(config.httpClient, req)
+	if err != nil {
+		return nil, err
+	}
+
+	var summaries []*artifactSummary
+	if err := json.Unmarshal(b, &summaries); err != nil {
+		return nil, err
+	}
+	return summaries, nil
+}
@@ -0,0 +1,110 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cdap
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+)
+
+// dataSourceConfig reads the configuration of the instance, such as the
+// values of cdap-site.xml. CDAP only exposes the configuration for reading,
+// so it cannot be managed as a resource.
+// https://docs.cdap.io/cdap/current/en/reference-manual/http-restful-api/configuration.html
+func dataSourceConfig() *schema.Resource {
+	return &schema.Resource{
+		Read: dataSourceConfigRead,
+
+		Schema: map[string]*schema.Schema{
+			"type": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				Default:      "cdap",
+				ValidateFunc: validation.StringInSlice([]string{"cdap", "hadoop"}, false),
+				Description:  "The configuration to read, either cdap for cdap-site.xml and its defaults or hadoop for the Hadoop configuration of the instance.",
+			},
+			"keys": {
+				Type:        schema.TypeSet,
+				Optional:    true,
+				Description: "The configuration keys to read. It is an error if one of them is not set. If not provided, all keys are read.",
+				Elem:        &schema.Schema{Type: schema.TypeString},
+			},
+			"values": {
+				Type:        schema.TypeMap,
+				Computed:    true,
+				Description: "The values of the configuration keys.",
+				Elem: &schema.Schema{
+					Type: schema.TypeString,
+				},
+			},
+			"sources": {
+				Type:        schema.TypeMap,
+				Computed:    true,
+				Description: "The file each configuration key is set in, such as cdap-default.xml or cdap-site.xml.",
+				Elem: &schema.Schema{
+					Type: schema.TypeString,
+				},
+			},
+		},
+	}
+}
+
+// configEntry is a configuration key as returned by CDAP.
+type configEntry struct {
+	Name   string `json:"name"`
+	Value  string `json:"value"`
+	Source string `json:"source"`
+}
+
+func dataSourceConfigRead(d *schema.ResourceData, m interface{}) error {
+	config := m.(*Config)
+	typ := d.Get("type").(string)
+
+	var entries []*configEntry
+	if err := getJSON(config, urlJoin(config.host, "/v3/config", typ)+"?format=json", &entries); err != nil {
+		return permissionError(err, fmt.Sprintf("read %s configuration", typ))
+	}
+
+	keys := d.Get("keys").(*schema.Set)
+	values := make(map[string]string)
+	sources := make(map[string]string)
+	for _, e := range entries {
+		if keys.Len() > 0 && !keys.Contains(e.Name) {
+			continue
+		}
+		values[e.Name] = e.Value
+		sources[e.Name] = e.Source
+	}
+
+	var missing []string
+	for _, k := range setToStrings(keys) {
+		if _, ok := values[k]; !ok {
+			missing = append(missing, k)
+		}
+	}
+	if len(missing) > 0 {
+		sort.Strings(missing)
+		return fmt.Errorf("%s configuration keys not set: %s", typ, strings.Join(missing, ", "))
+	}
+
+	d.Set("values", values)
+	d.Set("sources", sources)
+	d.SetId(typ)
+	return nil
+}
@@ -0,0 +1,92 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cdap
+
+import (
+	"fmt"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+// dataSourceDataset looks up an existing dataset, such as one provisioned by
+// another team, without managing it.
+// https://docs.cdap.io/cdap/current/en/reference-manual/http-restful-api/dataset.html
+func dataSourceDataset() *schema.Resource {
+	return &schema.Resource{
+		Read: dataSourceDatasetRead,
+
+		Schema: map[string]*schema.Schema{
+			"name": {
+				Type:        schema.TypeString,
+				Required:    true,
+				Description: "The name of the dataset.",
+			},
+			"namespace": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Computed:    true,
+				Description: "The name of the namespace in which the dataset belongs. If not provided, the provider's default_namespace is used.",
+			},
+			"type": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "The type of the dataset, such as table or fileSet.",
+			},
+			"description": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "The description of the dataset.",
+			},
+			"properties": {
+				Type:        schema.TypeMap,
+				Computed:    true,
+				Description: "The properties the dataset was created with.",
+				Elem: &schema.Schema{
+					Type: schema.TypeString,
+				},
+			},
+			"schema": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "The schema of the dataset as JSON, taken from its schema property. Empty if the dataset has none.",
+			},
+		},
+	}
+}
+
+func dataSourceDatasetRead(d *schema.ResourceData, m interface{}) error {
+	config := m.(*Config)
+	if err := setDefaultNamespace(d, config); err != nil {
+		return err
+	}
+	name := d.Get("name").(string)
+	namespace := d.Get("namespace").(string)
+
+	meta := new(datasetMeta)
+	err := getJSON(config, urlJoin(config.host, "/v3/namespaces", namespace, "/data/datasets", name), meta)
+	if isNotFound(err) {
+		return fmt.Errorf("dataset %q not found in namespace %q", name, namespace)
+	}
+	if err != nil {
+		return err
+	}
+
+	d.Set("type", meta.Spec.Type)
+	d.Set("description", meta.Spec.Description)
+	d.Set("properties", meta.Spec.OriginalProperties)
+	d.Set("schema", meta.Spec.OriginalProperties["schema"])
+	d.SetId(namespace + "/" + name)
+	return nil
+}
@@ -0,0 +1,222 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cdap
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+)
+
+// dataSourceLineage returns the programs that read and wrote a dataset over
+// a time range, for example to build audit outputs. CDAP only records lineage
+// of datasets, so the lineage of a program is found through the datasets it
+// accessed.
+// https://docs.cdap.io/cdap/current/en/reference-manual/http-restful-api/metadata.html
+func dataSourceLineage() *schema.Resource {
+	return &schema.Resource{
+		Read: dataSourceLineageRead,
+
+		Schema: map[string]*schema.Schema{
+			"namespace": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Computed:    true,
+				Description: "The name of the namespace of the dataset. If not provided, the provider's default_namespace is used.",
+			},
+			"dataset": {
+				Type:        schema.TypeString,
+				Required:    true,
+				Description: "The name of the dataset to return the lineage of.",
+			},
+			"start": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Default:     "now-7d",
+				Description: "The start of the time range, as a timestamp in seconds or relative to now such as now-1d.",
+			},
+			"end": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Default:     "now",
+				Description: "The end of the time range, as a timestamp in seconds or relative to now such as now.",
+			},
+			"levels": {
+				Type:         schema.TypeInt,
+				Optional:     true,
+				Default:      10,
+				ValidateFunc: validation.IntAtLeast(1),
+				Description:  "The number of levels of upstream and downstream relationships to return.",
+			},
+			"edges": {
+				Type:        schema.TypeList,
+				Computed:    true,
+				Description: "The edges of the lineage graph, each from a dataset to a program reading it or from a program to a dataset it wrote. A program both reading and writing a dataset has an edge in each direction.",
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"from": {
+							Type:        schema.TypeString,
+							Computed:    true,
+							Description: "The ID of the node the data flows from, such as dataset.default.purchases.",
+						},
+						"to": {
+							Type:        schema.TypeString,
+							Computed:    true,
+							Description: "The ID of the node the data flows to, such as program.default.PurchaseHistory.-SNAPSHOT.mapreduce.PurchaseHistoryBuilder.",
+						},
+						"access": {
+							Type:        schema.TypeString,
+							Computed:    true,
+							Description: "How the program accessed the dataset, one of read, write, read_write or unknown.",
+						},
+						"dataset_namespace": {
+							Type:        schema.TypeString,
+							Computed:    true,
+							Description: "The namespace of the dataset.",
+						},
+						"dataset": {
+							Type:        schema.TypeString,
+							Computed:    true,
+							Description: "The name of the dataset.",
+						},
+						"program_namespace": {
+							Type:        schema.TypeString,
+							Computed:    true,
+							Description: "The namespace of the program.",
+						},
+						"app": {
+							Type:        schema.TypeString,
+							Computed:    true,
+							Description: "The application of the program.",
+						},
+						"program_type": {
+							Type:        schema.TypeString,
+							Computed:    true,
+							Description: "The type of the program, such as mapreduce or workflow.",
+						},
+						"program": {
+							Type:        schema.TypeString,
+							Computed:    true,
+							Description: "The name of the program.",
+						},
+						"run_ids": {
+							Type:        schema.TypeList,
+							Computed:    true,
+							Description: "The runs of the program that accessed the dataset in the time range.",
+							Elem: &schema.Schema{
+								Type: schema.TypeString,
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+// lineageEntity is the entity ID of a node of the lineage graph.
+type lineageEntity struct {
+	EntityID struct {
+		Namespace   string `json:"namespace"`
+		Dataset     string `json:"dataset"`
+		Application string `json:"application"`
+		Type        string `json:"type"`
+		Program     string `json:"program"`
+	} `json:"entityId"`
+}
+
+// lineageResult is the subset of the lineage response used by this provider.
+type lineageResult struct {
+	Relations []struct {
+		Data    string   `json:"data"`
+		Program string   `json:"program"`
+		Access  string   `json:"access"`
+		Runs    []string `json:"runs"`
+	} `json:"relations"`
+	Programs map[string]*lineageEntity `json:"programs"`
+	Data     map[string]*lineageEntity `json:"data"`
+}
+
+func dataSourceLineageRead(d *schema.ResourceData, m interface{}) error {
+	config := m.(*Config)
+	if err := setDefaultNamespace(d, config); err != nil {
+		return err
+	}
+	namespace := d.Get("namespace").(string)
+	dataset := d.Get("dataset").(string)
+	start, end, levels := d.Get("start").(string), d.Get("end").(string), d.Get("levels").(int)
+
+	q := url.Values{}
+	q.Set("start", start)
+	q.Set("end", end)
+	q.Set("levels", strconv.Itoa(levels))
+	result := new(lineageResult)
+	err := getJSON(config, urlJoin(config.host, "/v3/namespaces", namespace, "/datasets", dataset, "/lineage")+"?"+q.Encode(), result)
+	if isNotFound(err) {
+		return fmt.Errorf("dataset %q not found in namespace %q", dataset, namespace)
+	}
+	if err != nil {
+		return permissionError(err, fmt.Sprintf("read lineage of dataset %q", dataset))
+	}
+
+	edges := make([]map[string]interface{}, 0, len(result.Relations))
+	for _, r := range result.Relations {
+		access := strings.ToLower(r.Access)
+		edge := func(from, to string) map[string]interface{} {
+			e := map[string]interface{}{
+				"from":    from,
+				"to":      to,
+				"access":  access,
+				"run_ids": r.Runs,
+			}
+			if data, ok := result.Data[r.Data]; ok {
+				e["dataset_namespace"] = data.EntityID.Namespace
+				e["dataset"] = data.EntityID.Dataset
+			}
+			if p, ok := result.Programs[r.Program]; ok {
+				e["program_namespace"] = p.EntityID.Namespace
+				e["app"] = p.EntityID.Application
+				e["program_type"] = strings.ToLower(p.EntityID.Type)
+				e["program"] = p.EntityID.Program
+			}
+			return e
+		}
+		// Relations with an unknown access are reported as reads.
+		if access != "write" {
+			edges = append(edges, edge(r.Data, r.Program))
+		}
+		if access == "write" || access == "read_write" {
+			edges = append(edges, edge(r.Program, r.Data))
+		}
+	}
+	// CDAP returns the relations in no particular order.
+	sort.SliceStable(edges, func(i, j int) bool {
+		if edges[i]["from"] != edges[j]["from"] {
+			return edges[i]["from"].(string) < edges[j]["from"].(string)
+		}
+		return edges[i]["to"].(string) < edges[j]["to"].(string)
+	})
+
+	if err := d.Set("edges", edges); err != nil {
+		return err
+	}
+	d.SetId(strings.Join([]string{namespace, dataset, start, end, strconv.Itoa(levels)}, "/"))
+	return nil
+}
@@ -0,0 +1,207 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cdap
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+)
+
+// dataSourceMetrics queries metrics such as the number of records processed
+// by a program, for example to only act once a pipeline is idle.
+// https://docs.cdap.io/cdap/current/en/reference-manual/http-restful-api/metrics.html
+func dataSourceMetrics() *schema.Resource {
+	return &schema.Resource{
+		Read: dataSourceMetricsRead,
+
+		Schema: map[string]*schema.Schema{
+			"tags": {
+				Type:        schema.TypeMap,
+				Required:    true,
+				Description: "The tags selecting the context of the metrics, such as namespace, app and workflow.",
+				Elem: &schema.Schema{
+					Type: schema.TypeString,
+				},
+			},
+			"metrics": {
+				Type:        schema.TypeList,
+				Required:    true,
+				MinItems:    1,
+				Description: "The names of the metrics to query, such as system.process.events.processed.",
+				Elem: &schema.Schema{
+					Type: schema.TypeString,
+				},
+			},
+			"start": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Description: "The start of the time range, as a timestamp in seconds or relative to now such as now-1h. If not provided, CDAP uses the start of the available data.",
+			},
+			"end": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Description: "The end of the time range, as a timestamp in seconds or relative to now such as now. If not provided, CDAP uses now.",
+			},
+			"resolution": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				ValidateFunc: validation.StringInSlice([]string{"1s", "1m", "1h", "auto"}, false),
+				Description:  "The resolution of the data points, one of 1s, 1m, 1h or auto.",
+			},
+			"aggregate": {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Default:     false,
+				Description: "Whether to return a single data point per metric aggregating the whole time range, instead of a time series.",
+			},
+			"series": {
+				Type:        schema.TypeList,
+				Computed:    true,
+				Description: "The time series returned by CDAP. Empty if there is no data for the metrics.",
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"metric": {
+							Type:        schema.TypeString,
+							Computed:    true,
+							Description: "The name of the metric.",
+						},
+						"grouping": {
+							Type:        schema.TypeMap,
+							Computed:    true,
+							Description: "The tag values this series is grouped by.",
+							Elem: &schema.Schema{
+								Type: schema.TypeString,
+							},
+						},
+						"data": {
+							Type:        schema.TypeList,
+							Computed:    true,
+							Description: "The data points of the series.",
+							Elem: &schema.Resource{
+								Schema: map[string]*schema.Schema{
+									"time": {
+										Type:        schema.TypeInt,
+										Computed:    true,
+										Description: "The time of the data point in seconds since the epoch.",
+									},
+									"value": {
+										Type:        schema.TypeFloat,
+										Computed:    true,
+										Description: "The value of the data point.",
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+			"totals": {
+				Type:        schema.TypeMap,
+				Computed:    true,
+				Description: "The sum of all data points by metric name. Metrics without data are 0.",
+				Elem: &schema.Schema{
+					Type: schema.TypeFloat,
+				},
+			},
+		},
+	}
+}
+
+// metricsQueryResult is the subset of the metrics query response used by this provider.
+type metricsQueryResult struct {
+	Series []struct {
+		MetricName string            `json:"metricName"`
+		Grouping   map[string]string `json:"grouping"`
+		Data       []struct {
+			Time  int64   `json:"time"`
+			Value float64 `json:"value"`
+		} `json:"data"`
+	} `json:"series"`
+}
+
+func dataSourceMetricsRead(d *schema.ResourceData, m interface{}) error {
+	config := m.(*Config)
+
+	// Sort the tags so the query, which is also the ID, does not depend on map order.
+	tags := d.Get("tags").(map[string]interface{})
+	var names []string
+	for k := range tags {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+
+	q := url.Values{}
+	for _, k := range names {
+		q.Add("tag", k+":"+tags[k].(string))
+	}
+	totals := make(map[string]interface{})
+	for _, v := range d.Get("metrics").([]interface{}) {
+		q.Add("metric", v.(string))
+		totals[v.(string)] = 0.0
+	}
+	for _, k := range []string{"start", "end", "resolution"} {
+		if v, ok := d.GetOk(k); ok {
+			q.Set(k, v.(string))
+		}
+	}
+	if d.Get("aggregate").(bool) {
+		q.Set("aggregate", strconv.FormatBool(true))
+	}
+
+	req, err := http.NewRequest(http.MethodPost, urlJoin(config.host, "/v3/metrics/query")+"?"+q.Encode(), nil)
+	if err != nil {
+		return err
+	}
+	b, err := httpCall(config.httpClient, req)
+	if err != nil {
+		return err
+	}
+	result := new(metricsQueryResult)
+	if err := json.Unmarshal(b, result); err != nil {
+		return err
+	}
+
+	series := make([]map[string]interface{}, 0, len(result.Series))
+	for _, s := range result.Series {
+		data := make([]map[string]interface{}, 0, len(s.Data))
+		var total float64
+		for _, p := range s.Data {
+			data = append(data, map[string]interface{}{
+				"time":  int(p.Time),
+				"value": p.Value,
+			})
+			total += p.Value
+		}
+		series = append(series, map[string]interface{}{
+			"metric":   s.MetricName,
+			"grouping": s.Grouping,
+			"data":     data,
+		})
+		if t, ok := totals[s.MetricName].(float64); ok {
+			totals[s.MetricName] = t + total
+		}
+	}
+
+	d.Set("series", series)
+	d.Set("totals", totals)
+	d.SetId(q.Encode())
+	return nil
+}
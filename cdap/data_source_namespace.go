@@ -0,0 +1,145 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cdap
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+// dataSourceNamespace summarizes a namespace. Counting its contents takes a
+// list call each, so the counts are only fetched when asked for.
+// https://docs.cdap.io/cdap/current/en/reference-manual/http-restful-api/namespace.html
+func dataSourceNamespace() *schema.Resource {
+	return &schema.Resource{
+		Read: dataSourceNamespaceRead,
+
+		Schema: map[string]*schema.Schema{
+			"name": {
+				Type:        schema.TypeString,
+				Required:    true,
+				Description: "The name of the namespace.",
+			},
+			"description": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "The description of the namespace.",
+			},
+			"count_artifacts": {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Default:     false,
+				Description: "Whether to count the user scoped artifacts of the namespace into artifact_count.",
+			},
+			"count_apps": {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Default:     false,
+				Description: "Whether to count the applications of the namespace into app_count.",
+			},
+			"count_datasets": {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Default:     false,
+				Description: "Whether to count the datasets of the namespace into dataset_count.",
+			},
+			"artifact_count": {
+				Type:        schema.TypeInt,
+				Computed:    true,
+				Description: "The number of artifact versions in the namespace, if count_artifacts is set.",
+			},
+			"app_count": {
+				Type:        schema.TypeInt,
+				Computed:    true,
+				Description: "The number of applications in the namespace, if count_apps is set.",
+			},
+			"dataset_count": {
+				Type:        schema.TypeInt,
+				Computed:    true,
+				Description: "The number of datasets in the namespace, if count_datasets is set.",
+			},
+		},
+	}
+}
+
+func dataSourceNamespaceRead(d *schema.ResourceData, m interface{}) error {
+	config := m.(*Config)
+	name := d.Get("name").(string)
+
+	req, err := http.NewRequest(http.MethodGet, urlJoin(config.host, "/v3/namespaces", name), nil)
+	if err != nil {
+		return err
+	}
+
+	b, err := httpCall(config.httpClient, req)
+	if isNotFound(err) {
+		return fmt.Errorf("namespace %q not found", name)
+	}
+	if err != nil {
+		return err
+	}
+
+	meta := new(namespaceMeta)
+	if err := json.Unmarshal(b, meta); err != nil {
+		return err
+	}
+	d.Set("description", meta.Description)
+
+	if d.Get("count_artifacts").(bool) {
+		artifacts, err := listArtifacts(config, name, "user")
+		if err != nil {
+			return fmt.Errorf("failed to list artifacts of namespace %q: %v", name, err)
+		}
+		d.Set("artifact_count", len(artifacts))
+	}
+	for _, c := range []struct{ flag, count, kind, path string }{
+		{"count_apps", "app_count", "applications", "/apps"},
+		{"count_datasets", "dataset_count", "datasets", "/data/datasets"},
+	} {
+		if !d.Get(c.flag).(bool) {
+			continue
+		}
+		n, err := countItems(config, urlJoin(config.host, "/v3/namespaces", name, c.path))
+		if err != nil {
+			return fmt.Errorf("failed to list %s of namespace %q: %v", c.kind, name, err)
+		}
+		d.Set(c.count, n)
+	}
+
+	d.SetId(name)
+	return nil
+}
+
+// countItems returns the length of the JSON list returned by addr.
+func countItems(config *Config, addr string) (int, error) {
+	req, err := http.NewRequest(http.MethodGet, addr, nil)
+	if err != nil {
+		return 0, err
+	}
+
+	b, err := httpCall(config.httpClient, req)
+	if err != nil {
+		return 0, err
+	}
+
+	var items []json.RawMessage
+	if err := json.Unmarshal(b, &items); err != nil {
+		return 0, err
+	}
+	return len(items), nil
+}
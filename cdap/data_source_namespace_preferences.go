@@ -0,0 +1,71 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cdap
+
+import (
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+// https://docs.cdap.io/cdap/current/en/reference-manual/http-restful-api/preferences.html
+func dataSourceNamespacePreferences() *schema.Resource {
+	return &schema.Resource{
+		Read: dataSourceNamespacePreferencesRead,
+
+		Schema: map[string]*schema.Schema{
+			"namespace": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Computed:    true,
+				Description: "The name of the namespace to read the preferences of. If not provided, the provider's default_namespace is used.",
+			},
+			"resolved": {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Default:     false,
+				Description: "Whether to include the preferences inherited from the instance.",
+			},
+			"preferences": {
+				Type:        schema.TypeMap,
+				Computed:    true,
+				Description: "The preferences of the namespace. Empty if none are set.",
+				Elem: &schema.Schema{
+					Type: schema.TypeString,
+				},
+			},
+		},
+	}
+}
+
+func dataSourceNamespacePreferencesRead(d *schema.ResourceData, m interface{}) error {
+	config := m.(*Config)
+	if err := setDefaultNamespace(d, config); err != nil {
+		return err
+	}
+	namespace := d.Get("namespace").(string)
+
+	addr := urlJoin(config.host, "/v3/namespaces", namespace, "/preferences")
+	if d.Get("resolved").(bool) {
+		addr += "?resolved=true"
+	}
+
+	prefs, err := getPreferences(config, addr)
+	if err != nil {
+		return err
+	}
+
+	d.Set("preferences", prefs)
+	d.SetId(namespace)
+	return nil
+}
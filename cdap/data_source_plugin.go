@@ -0,0 +1,250 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cdap
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+)
+
+// dataSourcePlugin looks up a plugin available to applications of a parent
+// artifact, such as cdap-data-pipeline, to check that it exists before it is
+// referenced in an application config.
+// https://docs.cdap.io/cdap/current/en/reference-manual/http-restful-api/artifact.html#retrieve-plugin-details
+func dataSourcePlugin() *schema.Resource {
+	return &schema.Resource{
+		Read: dataSourcePluginRead,
+
+		Schema: map[string]*schema.Schema{
+			"artifact": {
+				Type:        schema.TypeString,
+				Required:    true,
+				Description: "The name of the parent artifact, such as cdap-data-pipeline.",
+			},
+			"artifact_version": {
+				Type:        schema.TypeString,
+				Required:    true,
+				Description: "The version of the parent artifact.",
+			},
+			"artifact_scope": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				Default:      "system",
+				ValidateFunc: validation.StringInSlice([]string{"user", "system"}, false),
+				Description:  "The scope of the parent artifact, either user or system.",
+			},
+			"namespace": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Computed:    true,
+				Description: "The name of the namespace to look up the plugin in. If not provided, the provider's default_namespace is used.",
+			},
+			"type": {
+				Type:        schema.TypeString,
+				Required:    true,
+				Description: "The type of the plugin, such as batchsource or transform.",
+			},
+			"name": {
+				Type:        schema.TypeString,
+				Required:    true,
+				Description: "The name of the plugin.",
+			},
+			"plugin_artifact": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Computed:    true,
+				Description: "The name of the artifact containing the plugin. If not provided and several artifacts contain it, the one with the highest version is used.",
+			},
+			"plugin_artifact_version": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "The version of the artifact containing the plugin.",
+			},
+			"plugin_artifact_scope": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "The scope of the artifact containing the plugin, either user or system.",
+			},
+			"plugin_types": {
+				Type:        schema.TypeList,
+				Computed:    true,
+				Description: "All plugin types available to the parent artifact.",
+				Elem: &schema.Schema{
+					Type: schema.TypeString,
+				},
+			},
+			"class_name": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "The fully qualified class name of the plugin.",
+			},
+			"description": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "The description of the plugin.",
+			},
+			"endpoints": {
+				Type:        schema.TypeList,
+				Computed:    true,
+				Description: "The names of the endpoints the plugin exposes.",
+				Elem: &schema.Schema{
+					Type: schema.TypeString,
+				},
+			},
+			"properties": {
+				Type:        schema.TypeList,
+				Computed:    true,
+				Description: "The properties of the plugin, sorted by name.",
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"name": {
+							Type:        schema.TypeString,
+							Computed:    true,
+							Description: "The name of the property.",
+						},
+						"type": {
+							Type:        schema.TypeString,
+							Computed:    true,
+							Description: "The type of the property, such as string or int.",
+						},
+						"description": {
+							Type:        schema.TypeString,
+							Computed:    true,
+							Description: "The description of the property.",
+						},
+						"required": {
+							Type:        schema.TypeBool,
+							Computed:    true,
+							Description: "Whether the property must be set.",
+						},
+						"macro_supported": {
+							Type:        schema.TypeBool,
+							Computed:    true,
+							Description: "Whether the property may contain macros.",
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+// pluginDetail is an entry returned by the CDAP plugin detail endpoint.
+type pluginDetail struct {
+	Name        string                     `json:"name"`
+	Type        string                     `json:"type"`
+	Description string                     `json:"description"`
+	ClassName   string                     `json:"className"`
+	Artifact    *artifactSummary           `json:"artifact"`
+	Properties  map[string]*pluginProperty `json:"properties"`
+	Endpoints   []string                   `json:"endpoints"`
+}
+
+type pluginProperty struct {
+	Name           string `json:"name"`
+	Type           string `json:"type"`
+	Description    string `json:"description"`
+	Required       bool   `json:"required"`
+	MacroSupported bool   `json:"macroSupported"`
+}
+
+func dataSourcePluginRead(d *schema.ResourceData, m interface{}) error {
+	config := m.(*Config)
+	if err := setDefaultNamespace(d, config); err != nil {
+		return err
+	}
+	namespace := d.Get("namespace").(string)
+	typ := d.Get("type").(string)
+	name := d.Get("name").(string)
+	artifactAddr := urlJoin(config.host, "/v3/namespaces", namespace, "/artifacts", d.Get("artifact").(string), "/versions", d.Get("artifact_version").(string))
+	scope := "?scope=" + url.QueryEscape(strings.ToUpper(d.Get("artifact_scope").(string)))
+
+	var types []string
+	if err := getJSON(config, urlJoin(artifactAddr, "/extensions")+scope, &types); err != nil {
+		if isNotFound(err) {
+			return fmt.Errorf("artifact %q version %q not found: %v", d.Get("artifact"), d.Get("artifact_version"), err)
+		}
+		return err
+	}
+
+	var plugins []*pluginDetail
+	err := getJSON(config, urlJoin(artifactAddr, "/extensions", typ, "/plugins", name)+scope, &plugins)
+	if isNotFound(err) || (err == nil && len(plugins) == 0) {
+		return fmt.Errorf("plugin %q of type %q not found for artifact %q version %q", name, typ, d.Get("artifact"), d.Get("artifact_version"))
+	}
+	if err != nil {
+		return err
+	}
+
+	var p *pluginDetail
+	for _, c := range plugins {
+		if c.Artifact == nil {
+			continue
+		}
+		if a, ok := d.GetOk("plugin_artifact"); ok && c.Artifact.Name != a.(string) {
+			continue
+		}
+		if p == nil || compareVersions(c.Artifact.Version, p.Artifact.Version) > 0 {
+			p = c
+		}
+	}
+	if p == nil {
+		return fmt.Errorf("plugin %q of type %q is not contained in artifact %q", name, typ, d.Get("plugin_artifact"))
+	}
+
+	var props []map[string]interface{}
+	for _, prop := range p.Properties {
+		props = append(props, map[string]interface{}{
+			"name":            prop.Name,
+			"type":            prop.Type,
+			"description":     prop.Description,
+			"required":        prop.Required,
+			"macro_supported": prop.MacroSupported,
+		})
+	}
+	sort.Slice(props, func(i, j int) bool { return props[i]["name"].(string) < props[j]["name"].(string) })
+
+	d.Set("plugin_types", types)
+	d.Set("plugin_artifact", p.Artifact.Name)
+	d.Set("plugin_artifact_version", p.Artifact.Version)
+	d.Set("plugin_artifact_scope", strings.ToLower(p.Artifact.Scope))
+	d.Set("class_name", p.ClassName)
+	d.Set("description", p.Description)
+	d.Set("endpoints", p.Endpoints)
+	d.Set("properties", props)
+	d.SetId(strings.Join([]string{namespace, d.Get("artifact").(string), d.Get("artifact_version").(string), typ, name}, "/"))
+	return nil
+}
+
+// getJSON fetches addr and decodes the JSON response into v.
+func getJSON(config *Config, addr string, v interface{}) error {
+	req, err := http.NewRequest(http.MethodGet, addr, nil)
+	if err != nil {
+		return err
+	}
+
+	b, err := httpCall(config.httpClient, req)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, v)
+}
@@ -0,0 +1,132 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cdap
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+// dataSourceServiceEndpoint returns the URL a running service is served at
+// through the router of the instance.
+// https://docs.cdap.io/cdap/current/en/reference-manual/http-restful-api/service.html
+func dataSourceServiceEndpoint() *schema.Resource {
+	return &schema.Resource{
+		Read: dataSourceServiceEndpointRead,
+
+		Schema: map[string]*schema.Schema{
+			"namespace": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Computed:    true,
+				Description: "The name of the namespace in which the application belongs. If not provided, the provider's default_namespace is used.",
+			},
+			"app": {
+				Type:        schema.TypeString,
+				Required:    true,
+				Description: "The name of the application.",
+			},
+			"service": {
+				Type:        schema.TypeString,
+				Required:    true,
+				Description: "The name of the service.",
+			},
+			"url": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "The base URL of the service methods. Requests to it need the same credentials as the provider.",
+			},
+			"endpoints": {
+				Type:        schema.TypeList,
+				Computed:    true,
+				Description: "The endpoints exposed by the handlers of the service, relative to url.",
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"method": {
+							Type:        schema.TypeString,
+							Computed:    true,
+							Description: "The HTTP method of the endpoint.",
+						},
+						"path": {
+							Type:        schema.TypeString,
+							Computed:    true,
+							Description: "The path of the endpoint.",
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+// serviceSpec is the subset of the CDAP service specification used by this provider.
+type serviceSpec struct {
+	Handlers map[string]struct {
+		Endpoints []struct {
+			Method string `json:"method"`
+			Path   string `json:"path"`
+		} `json:"endpoints"`
+	} `json:"handlers"`
+}
+
+func dataSourceServiceEndpointRead(d *schema.ResourceData, m interface{}) error {
+	config := m.(*Config)
+	if err := setDefaultNamespace(d, config); err != nil {
+		return err
+	}
+	namespace, app, service := d.Get("namespace").(string), d.Get("app").(string), d.Get("service").(string)
+	addr := urlJoin(config.host, "/v3/namespaces", namespace, "/apps", app, "/services", service)
+
+	spec := new(serviceSpec)
+	err := getJSON(config, addr, spec)
+	if isNotFound(err) {
+		return fmt.Errorf("service %q of application %q not found in namespace %q", service, app, namespace)
+	}
+	if err != nil {
+		return err
+	}
+
+	var status struct {
+		Status string `json:"status"`
+	}
+	if err := getJSON(config, urlJoin(addr, "/status"), &status); err != nil {
+		return fmt.Errorf("failed to get status of service %q: %v", service, err)
+	}
+	if status.Status != "RUNNING" {
+		return fmt.Errorf("service %q of application %q is %s, start it to get its endpoint", service, app, status.Status)
+	}
+
+	var handlers []string
+	for h := range spec.Handlers {
+		handlers = append(handlers, h)
+	}
+	sort.Strings(handlers)
+	var endpoints []map[string]interface{}
+	for _, h := range handlers {
+		for _, e := range spec.Handlers[h].Endpoints {
+			endpoints = append(endpoints, map[string]interface{}{
+				"method": e.Method,
+				"path":   e.Path,
+			})
+		}
+	}
+
+	d.Set("url", urlJoin(addr, "/methods"))
+	d.Set("endpoints", endpoints)
+	d.SetId(namespace + "/" + app + "/" + service)
+	return nil
+}
@@ -0,0 +1,106 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cdap
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// cachedFile holds what was read from a local file, as long as its size and
+// modification time do not change. Only the results are kept rather than the
+// contents, since JARs can be large and uploads stream them from disk.
+type cachedFile struct {
+	size    int64
+	modTime time.Time
+
+	// mu serializes reads of the file, so concurrent resources using the
+	// same file read it once.
+	mu     sync.Mutex
+	sha256 string
+	// manifestVersion is only valid if manifestRead is set, since JARs may
+	// have no version.
+	manifestVersion string
+	manifestRead    bool
+}
+
+// fileCache caches reads of local files by path, so the same JAR used by many
+// resources, such as one artifact uploaded to many namespaces with for_each,
+// is only read once per apply. A file whose size or modification time changed
+// is read again.
+var fileCache = struct {
+	sync.Mutex
+	files map[string]*cachedFile
+}{files: make(map[string]*cachedFile)}
+
+// cachedFileFor returns the cache entry of the file at path with the given
+// info, replacing the entry of a previous version of the file.
+func cachedFileFor(path string, fi os.FileInfo) *cachedFile {
+	fileCache.Lock()
+	defer fileCache.Unlock()
+	if c, ok := fileCache.files[path]; ok && c.size == fi.Size() && c.modTime.Equal(fi.ModTime()) {
+		return c
+	}
+	c := &cachedFile{size: fi.Size(), modTime: fi.ModTime()}
+	fileCache.files[path] = c
+	return c
+}
+
+// fileSHA256 returns the hex encoded SHA-256 checksum of the file.
+func fileSHA256(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil {
+		return "", err
+	}
+
+	c := cachedFileFor(path, fi)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.sha256 != "" {
+		return c.sha256, nil
+	}
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	c.sha256 = hex.EncodeToString(h.Sum(nil))
+	return c.sha256, nil
+}
+
+// jarFileManifestVersion returns the version in the manifest of the opened
+// JAR at path, as jarManifestVersion does.
+func jarFileManifestVersion(path string, f *os.File, fi os.FileInfo) (string, error) {
+	c := cachedFileFor(path, fi)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.manifestRead {
+		return c.manifestVersion, nil
+	}
+	v, err := jarManifestVersion(f, fi.Size())
+	if err != nil {
+		return "", err
+	}
+	c.manifestVersion, c.manifestRead = v, true
+	return v, nil
+}
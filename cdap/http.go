@@ -15,43 +15,373 @@
 package cdap
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"log"
+	"math/rand"
+	"net"
 	"net/http"
-	"path"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
 )
 
+// retryableStatusCodes are returned by load balancers in front of CDAP
+// while instances are restarting, or by gateways enforcing quotas.
+var retryableStatusCodes = map[int]bool{
+	http.StatusTooManyRequests:    true,
+	http.StatusBadGateway:         true,
+	http.StatusServiceUnavailable: true,
+	http.StatusGatewayTimeout:     true,
+}
+
+// apiClient is an http.Client along with the provider level settings
+// httpCall applies to every request.
+type apiClient struct {
+	*http.Client
+	maxRetries   int
+	retryMaxWait time.Duration
+	// headers are added to every request, for gateways in front of CDAP
+	// that require API keys or routing headers.
+	headers map[string]string
+	// userAgent is sent with every request unless headers sets one.
+	userAgent string
+	// stopCtx is cancelled when Terraform is interrupted, which aborts
+	// requests and retries in progress.
+	stopCtx context.Context
+	// inFlight bounds the number of concurrent requests across all
+	// resources of the provider. It is nil if there is no limit.
+	inFlight chan struct{}
+}
+
+// acquire waits for a free request slot, or returns the context's error if it
+// is done first.
+func (c *apiClient) acquire(ctx context.Context) error {
+	if c.inFlight == nil {
+		return nil
+	}
+	select {
+	case c.inFlight <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// release frees the request slot taken by acquire.
+func (c *apiClient) release() {
+	if c.inFlight != nil {
+		<-c.inFlight
+	}
+}
+
+// setHeaders adds the client's headers to the request, keeping the ones
+// already set by the caller.
+func (c *apiClient) setHeaders(req *http.Request) {
+	for k, v := range c.headers {
+		if req.Header.Get(k) == "" {
+			req.Header.Set(k, v)
+		}
+	}
+	if c.userAgent != "" && req.Header.Get("User-Agent") == "" {
+		req.Header.Set("User-Agent", c.userAgent)
+	}
+}
+
+// maxErrorBodyLen bounds how much of a response body is included in errors.
+const maxErrorBodyLen = 1024
+
 type httpError struct {
-	code int
-	body string
+	method string
+	url    string
+	code   int
+	body   string
+	// retryAfter is the wait requested by the Retry-After header, if any.
+	retryAfter time.Duration
 }
 
 func (e *httpError) Error() string {
-	return fmt.Sprintf("%v: %v", e.code, e.body)
+	return fmt.Sprintf("%v %v failed with status %v: %v", e.method, e.url, e.code, errorMessage(e.body))
 }
 
+// errorMessage returns the message of a CDAP error body. CDAP replies with
+// either plain text or a JSON object with a message field.
+func errorMessage(body string) string {
+	var v struct {
+		Message string `json:"message"`
+	}
+	if err := json.Unmarshal([]byte(body), &v); err == nil && v.Message != "" {
+		body = v.Message
+	}
+	body = strings.TrimSpace(body)
+	if len(body) > maxErrorBodyLen {
+		body = body[:maxErrorBodyLen] + "... (truncated)"
+	}
+	return body
+}
+
+// isNotFound reports whether err is an httpError for a missing resource.
+func isNotFound(err error) bool {
+	var httpErr *httpError
+	return errors.As(err, &httpErr) && httpErr.code == http.StatusNotFound
+}
+
+// isForbidden reports whether err is an httpError for a request the token
+// is not allowed to make.
+func isForbidden(err error) bool {
+	var httpErr *httpError
+	return errors.As(err, &httpErr) && (httpErr.code == http.StatusUnauthorized || httpErr.code == http.StatusForbidden)
+}
+
+// permissionError explains errors of requests the token is not allowed to
+// make, which would otherwise look like any other failure. Other errors are
+// returned unchanged.
+func permissionError(err error, action string) error {
+	if !isForbidden(err) {
+		return err
+	}
+	return fmt.Errorf("permission denied to %s, check that the token has access: %w", action, err)
+}
+
+// urlJoin joins the path segments onto the base URL with exactly one slash
+// between them, skipping empty segments. A query string in the last segment
+// is kept as is.
 func urlJoin(base string, paths ...string) string {
-	p := path.Join(paths...)
-	return fmt.Sprintf("%s/%s", strings.TrimRight(base, "/"), strings.TrimLeft(p, "/"))
+	var query string
+	var segments []string
+	for _, p := range paths {
+		if i := strings.Index(p, "?"); i >= 0 {
+			p, query = p[:i], p[i:]
+		}
+		for _, s := range strings.Split(p, "/") {
+			if s != "" {
+				segments = append(segments, s)
+			}
+		}
+	}
+
+	u := strings.TrimRight(base, "/")
+	if len(segments) > 0 {
+		u += "/" + strings.Join(segments, "/")
+	}
+	return u + query
 }
 
-func httpCall(client *http.Client, req *http.Request) ([]byte, error) {
-	log.Printf("%+v", req)
+// httpCall sends the request, retrying with exponential backoff on transient
+// errors, and returns the response body.
+//
+// All requests are retried, including POSTs such as artifact uploads. This is
+// safe because CDAP allows re-uploading an artifact with the same name and
+// version, and the other calls made by the provider are idempotent PUTs,
+// GETs and DELETEs.
+func httpCall(client *apiClient, req *http.Request) ([]byte, error) {
+	client.setHeaders(req)
+	if client.stopCtx != nil {
+		ctx, cancel := context.WithCancel(req.Context())
+		defer cancel()
+		go func() {
+			select {
+			case <-client.stopCtx.Done():
+				cancel()
+			case <-ctx.Done():
+			}
+		}()
+		req = req.WithContext(ctx)
+	}
+	var backoff time.Duration
+	for attempt := 0; ; attempt++ {
+		// The slot is not held while waiting to retry, so other requests can
+		// proceed in the meantime.
+		if err := client.acquire(req.Context()); err != nil {
+			return nil, err
+		}
+		b, err := doHTTPCall(client.Client, req)
+		client.release()
+		if err == nil || attempt >= client.maxRetries || !isRetryable(err) {
+			if attempt > 0 {
+				logRetries(req, attempt, backoff, err)
+			}
+			return b, err
+		}
 
+		// Requests with a body can only be retried if the body can be rewound.
+		if req.Body != nil {
+			if req.GetBody == nil {
+				return nil, err
+			}
+			body, bodyErr := req.GetBody()
+			if bodyErr != nil {
+				return nil, err
+			}
+			req.Body = body
+		}
+
+		wait := jitter(retryBackoff(attempt, client.retryMaxWait))
+		var httpErr *httpError
+		if errors.As(err, &httpErr) && httpErr.retryAfter > 0 {
+			// Waiting less than requested only uses up the quota again, so
+			// waits longer than the provider allows fail instead.
+			wait = httpErr.retryAfter
+			if wait > client.retryMaxWait {
+				return nil, fmt.Errorf("%v (the requested Retry-After of %v exceeds retry_max_wait_seconds of %v)", err, wait, client.retryMaxWait)
+			}
+			if deadline, ok := req.Context().Deadline(); ok && time.Until(deadline) < wait {
+				return nil, fmt.Errorf("%v (the requested Retry-After of %v exceeds the remaining timeout)", err, wait)
+			}
+		}
+		log.Printf("[DEBUG] retrying %v %v in %v after error: %v", req.Method, req.URL, wait, err)
+		if err := retrySleep(req.Context(), wait); err != nil {
+			return nil, err
+		}
+		backoff += wait
+	}
+}
+
+// logRetries summarizes the retries of a request, so flaky connectivity
+// shows up in the logs of an apply.
+func logRetries(req *http.Request, retries int, backoff time.Duration, err error) {
+	if err != nil {
+		log.Printf("[DEBUG] %v %v failed after %d retries, %v backoff: %v", req.Method, req.URL, retries, backoff.Round(100*time.Millisecond), err)
+		return
+	}
+	log.Printf("[DEBUG] %v %v succeeded after %d retries, %v backoff", req.Method, req.URL, retries, backoff.Round(100*time.Millisecond))
+}
+
+// retrySleep waits before a retry, returning early with the context's error
+// if it is done. It is a variable so the waits can be observed without
+// actually sleeping.
+var retrySleep = func(ctx context.Context, d time.Duration) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(d):
+		return nil
+	}
+}
+
+// jitterRand is seeded per process, so providers retrying against the same
+// instance do not wait in lockstep. rand.Rand is not safe for concurrent use,
+// hence the lock.
+var (
+	jitterMu   sync.Mutex
+	jitterRand = rand.New(rand.NewSource(time.Now().UnixNano()))
+)
+
+// jitter returns a random wait between 0 and max ("full jitter"), which
+// spreads out the retries of many resources failing at the same time, such
+// as during a CDAP restart.
+func jitter(max time.Duration) time.Duration {
+	if max <= 0 {
+		return 0
+	}
+	jitterMu.Lock()
+	defer jitterMu.Unlock()
+	return time.Duration(jitterRand.Int63n(int64(max) + 1))
+}
+
+func doHTTPCall(client *http.Client, req *http.Request) ([]byte, error) {
+	start := time.Now()
 	resp, err := client.Do(req)
 	if err != nil {
+		log.Printf("[DEBUG] %v %v failed after %v: %v", req.Method, req.URL, time.Since(start), err)
 		return nil, err
 	}
 	defer resp.Body.Close()
+	log.Printf("[DEBUG] %v %v returned %v in %v, request body: %v, headers: %v", req.Method, req.URL, resp.StatusCode, time.Since(start), bodySize(req), redactHeaders(req.Header))
 
 	b, err := ioutil.ReadAll(resp.Body)
 	if err != nil {
 		return nil, err
 	}
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		return nil, &httpError{code: resp.StatusCode, body: string(b)}
+		return nil, &httpError{
+			method:     req.Method,
+			url:        req.URL.String(),
+			code:       resp.StatusCode,
+			body:       string(b),
+			retryAfter: parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()),
+		}
 	}
 	return b, nil
 }
+
+// bodySize summarizes the size of the request body for logging, since bodies
+// such as JARs are too large to be logged.
+func bodySize(req *http.Request) string {
+	switch {
+	case req.Body == nil || req.Body == http.NoBody:
+		return "none"
+	case req.ContentLength < 0:
+		return "unknown size"
+	default:
+		return fmt.Sprintf("%d bytes", req.ContentLength)
+	}
+}
+
+// secretHeaderWords are parts of header names whose values are likely credentials.
+var secretHeaderWords = []string{"auth", "key", "token", "secret", "password", "cookie", "session"}
+
+// redactHeaders returns the headers with credentials removed for logging.
+func redactHeaders(h http.Header) http.Header {
+	redacted := h.Clone()
+	for k := range redacted {
+		name := strings.ToLower(k)
+		for _, w := range secretHeaderWords {
+			if strings.Contains(name, w) {
+				redacted[k] = []string{"REDACTED"}
+				break
+			}
+		}
+	}
+	return redacted
+}
+
+// parseRetryAfter returns the wait requested by a Retry-After header given
+// either in seconds or as an HTTP date, or 0 if there is none.
+func parseRetryAfter(v string, now time.Time) time.Duration {
+	if v == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(v); err == nil {
+		if secs < 0 {
+			return 0
+		}
+		return time.Duration(secs) * time.Second
+	}
+	t, err := http.ParseTime(v)
+	if err != nil || !t.After(now) {
+		return 0
+	}
+	return t.Sub(now)
+}
+
+// isRetryable reports whether err is a transient error worth retrying.
+func isRetryable(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	var httpErr *httpError
+	if errors.As(err, &httpErr) {
+		return retryableStatusCodes[httpErr.code]
+	}
+	// Connection level failures such as connection refused or reset.
+	var opErr *net.OpError
+	return errors.As(err, &opErr)
+}
+
+// retryBackoff returns the cap of the wait before the given retry attempt,
+// doubling from one second and capped at maxWait.
+func retryBackoff(attempt int, maxWait time.Duration) time.Duration {
+	wait := time.Second
+	for i := 0; i < attempt && wait < maxWait; i++ {
+		wait *= 2
+	}
+	if wait > maxWait {
+		wait = maxWait
+	}
+	return wait
+}
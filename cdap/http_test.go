@@ -0,0 +1,244 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cdap
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newTestConfig returns a provider config sending requests to a local server
+// replying with h, without retries.
+func newTestConfig(t *testing.T, h http.HandlerFunc) *Config {
+	srv := httptest.NewServer(h)
+	t.Cleanup(srv.Close)
+	return &Config{
+		host:             srv.URL,
+		defaultNamespace: "default",
+		httpClient:       &apiClient{Client: srv.Client(), stopCtx: context.Background()},
+	}
+}
+
+func TestURLJoin(t *testing.T) {
+	tests := []struct {
+		base  string
+		paths []string
+		want  string
+	}{
+		{"https://cdap.example.com", []string{"/v3/namespaces", "default"}, "https://cdap.example.com/v3/namespaces/default"},
+		{"https://cdap.example.com/", []string{"/v3/namespaces", "/default"}, "https://cdap.example.com/v3/namespaces/default"},
+		{"https://cdap.example.com/", []string{"v3/namespaces/", "default/"}, "https://cdap.example.com/v3/namespaces/default"},
+		{"https://cdap.example.com//", []string{"//v3//namespaces", "default"}, "https://cdap.example.com/v3/namespaces/default"},
+		{"https://cdap.example.com/api", []string{"/v3"}, "https://cdap.example.com/api/v3"},
+		{"https://cdap.example.com:11015", []string{"", "/v3", "", "apps"}, "https://cdap.example.com:11015/v3/apps"},
+		{"https://cdap.example.com", []string{"", "/"}, "https://cdap.example.com"},
+		{"https://cdap.example.com/", nil, "https://cdap.example.com"},
+		{"https://cdap.example.com", []string{"/v3/namespaces", "/artifacts?scope=SYSTEM"}, "https://cdap.example.com/v3/namespaces/artifacts?scope=SYSTEM"},
+		{"https://cdap.example.com/", []string{"/v3/runs/?limit=1&status=running"}, "https://cdap.example.com/v3/runs?limit=1&status=running"},
+	}
+	for _, tt := range tests {
+		if got := urlJoin(tt.base, tt.paths...); got != tt.want {
+			t.Errorf("urlJoin(%q, %q) = %q, want %q", tt.base, tt.paths, got, tt.want)
+		}
+	}
+}
+
+func TestJitter(t *testing.T) {
+	for _, max := range []time.Duration{time.Nanosecond, time.Second, 30 * time.Second} {
+		for i := 0; i < 1000; i++ {
+			if got := jitter(max); got < 0 || got > max {
+				t.Fatalf("jitter(%v) = %v, want between 0 and %v", max, got, max)
+			}
+		}
+	}
+	for _, max := range []time.Duration{0, -time.Second} {
+		if got := jitter(max); got != 0 {
+			t.Errorf("jitter(%v) = %v, want 0", max, got)
+		}
+	}
+}
+
+func TestHTTPCallRetryWaits(t *testing.T) {
+	const failures = 4
+	tests := []struct {
+		name       string
+		code       int
+		retryAfter func() string
+		// check reports whether the wait before the given retry is expected.
+		check   func(retry int, wait time.Duration) bool
+		wantErr bool
+	}{
+		{
+			name: "backoff",
+			code: http.StatusServiceUnavailable,
+			check: func(retry int, wait time.Duration) bool {
+				return wait >= 0 && wait <= retryBackoff(retry, 4*time.Second)
+			},
+		},
+		{
+			name:       "retry after seconds",
+			code:       http.StatusTooManyRequests,
+			retryAfter: func() string { return "2" },
+			check:      func(retry int, wait time.Duration) bool { return wait == 2*time.Second },
+		},
+		{
+			name:       "retry after date",
+			code:       http.StatusTooManyRequests,
+			retryAfter: func() string { return time.Now().Add(3 * time.Second).UTC().Format(http.TimeFormat) },
+			// HTTP dates have a resolution of one second.
+			check: func(retry int, wait time.Duration) bool { return wait > time.Second && wait <= 3*time.Second },
+		},
+		{
+			name:       "retry after exceeding the maximum wait",
+			code:       http.StatusTooManyRequests,
+			retryAfter: func() string { return "60" },
+			wantErr:    true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				calls++
+				if calls <= failures {
+					if tt.retryAfter != nil {
+						w.Header().Set("Retry-After", tt.retryAfter())
+					}
+					w.WriteHeader(tt.code)
+					return
+				}
+				w.Write([]byte("ok"))
+			}))
+			defer srv.Close()
+
+			var waits []time.Duration
+			defer func(orig func(context.Context, time.Duration) error) { retrySleep = orig }(retrySleep)
+			retrySleep = func(ctx context.Context, d time.Duration) error {
+				waits = append(waits, d)
+				return nil
+			}
+
+			client := &apiClient{Client: srv.Client(), maxRetries: failures, retryMaxWait: 4 * time.Second}
+			req, err := http.NewRequest(http.MethodGet, srv.URL, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			b, err := httpCall(client, req)
+			if tt.wantErr {
+				if err == nil || len(waits) != 0 {
+					t.Errorf("httpCall returned %q, %v after waiting %v, want an error without waiting", b, err, waits)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("httpCall returned error: %v", err)
+			}
+			if string(b) != "ok" {
+				t.Errorf("httpCall returned %q, want %q", b, "ok")
+			}
+			if len(waits) != failures {
+				t.Fatalf("httpCall waited %d times, want %d", len(waits), failures)
+			}
+			for i, w := range waits {
+				if !tt.check(i, w) {
+					t.Errorf("wait before retry %d = %v", i+1, w)
+				}
+			}
+		})
+	}
+}
+
+// Interrupting Terraform must abort a request waiting to be retried instead
+// of sleeping out the wait.
+func TestHTTPCallStopDuringRetryWait(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	stopCtx, stop := context.WithCancel(context.Background())
+	defer stop()
+	defer func(orig func(context.Context, time.Duration) error) { retrySleep = orig }(retrySleep)
+	orig := retrySleep
+	retrySleep = func(ctx context.Context, d time.Duration) error {
+		stop()
+		return orig(ctx, time.Hour)
+	}
+
+	client := &apiClient{Client: srv.Client(), maxRetries: 3, retryMaxWait: time.Second, stopCtx: stopCtx}
+	req, err := http.NewRequest(http.MethodGet, srv.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	done := make(chan error, 1)
+	go func() {
+		_, err := httpCall(client, req)
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("httpCall returned %v, want %v", err, context.Canceled)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("httpCall did not return after stopCtx was cancelled")
+	}
+}
+
+func TestErrorHelpers(t *testing.T) {
+	tests := []struct {
+		name          string
+		err           error
+		wantNotFound  bool
+		wantForbidden bool
+	}{
+		{name: "not found", err: &httpError{method: "GET", url: "http://cdap/v3/namespaces/team", code: http.StatusNotFound, body: "not found"}, wantNotFound: true},
+		{name: "unauthorized", err: &httpError{method: "GET", url: "http://cdap/v3/namespaces", code: http.StatusUnauthorized, body: "invalid token"}, wantForbidden: true},
+		{name: "forbidden", err: &httpError{method: "DELETE", url: "http://cdap/v3/namespaces/team", code: http.StatusForbidden, body: "access denied"}, wantForbidden: true},
+		{name: "wrapped forbidden", err: fmt.Errorf("failed: %w", &httpError{code: http.StatusForbidden}), wantForbidden: true},
+		{name: "server error", err: &httpError{method: "GET", url: "http://cdap/v3/namespaces", code: http.StatusInternalServerError, body: "boom"}},
+		{name: "not an http error", err: errors.New("connection refused")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isNotFound(tt.err); got != tt.wantNotFound {
+				t.Errorf("isNotFound() = %v, want %v", got, tt.wantNotFound)
+			}
+			if got := isForbidden(tt.err); got != tt.wantForbidden {
+				t.Errorf("isForbidden() = %v, want %v", got, tt.wantForbidden)
+			}
+
+			err := permissionError(tt.err, "delete namespace \"team\"")
+			if !tt.wantForbidden {
+				if err != tt.err {
+					t.Errorf("permissionError() = %v, want the error unchanged", err)
+				}
+				return
+			}
+			if !isForbidden(err) {
+				t.Errorf("permissionError() = %v, want it to wrap the %v", err, tt.err)
+			}
+			if want := "permission denied to delete namespace \"team\""; !strings.Contains(err.Error(), want) {
+				t.Errorf("permissionError() = %q, want it to contain %q", err, want)
+			}
+		})
+	}
+}
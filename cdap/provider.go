@@ -17,72 +17,505 @@ package cdap
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
 	"net/http"
+	"net/url"
+	"strings"
 	"time"
 
 	"cloud.google.com/go/storage"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
 	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/google"
 	"google.golang.org/api/option"
 )
 
 const defaultNamespace = "default"
 
+// Version is the version of the provider sent in the User-Agent of requests.
+// It is set by main from the version injected at build time.
+var Version = "dev"
+
 // Provider returns a terraform.ResourceProvider.
 func Provider() *schema.Provider {
-	return &schema.Provider{
+	p := &schema.Provider{
 		Schema: map[string]*schema.Schema{
 			"host": &schema.Schema{
 				Type:        schema.TypeString,
 				Required:    true,
-				Description: "The address of the CDAP instance.",
+				DefaultFunc: schema.MultiEnvDefaultFunc([]string{"CDAP_HOST", "CDAP_ROUTER_URL"}, nil),
+				Description: "The address of the CDAP instance, such as https://cdap.example.com:11015. Can also be set with the CDAP_HOST or CDAP_ROUTER_URL environment variables, in that order of precedence.",
+			},
+			"api_prefix": &schema.Schema{
+				Type:        schema.TypeString,
+				Optional:    true,
+				Default:     "",
+				Description: "A path prefix to add to host before the /v3 API paths, for instances behind a router that serves CDAP under a path such as /cdap.",
 			},
 			"token": &schema.Schema{
 				Type:        schema.TypeString,
 				Optional:    true,
-				Description: "The OAuth token to use for all http calls to the instance.",
+				DefaultFunc: schema.EnvDefaultFunc("CDAP_TOKEN", nil),
+				Description: "The OAuth token to use for all http calls to the instance. Can also be set with the CDAP_TOKEN environment variable.",
+			},
+			"token_file": &schema.Schema{
+				Type:        schema.TypeString,
+				Optional:    true,
+				Description: "The path to a file containing the OAuth token to use for all http calls to the instance. Cannot be used together with token.",
+			},
+			"google_auth": &schema.Schema{
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Default:     false,
+				Description: "Whether to authenticate to the instance with Google OAuth access tokens, as required by Cloud Data Fusion instances. Tokens are obtained from credentials_file if set, or from Application Default Credentials otherwise, for example through GOOGLE_APPLICATION_CREDENTIALS, and are refreshed before they expire. Cannot be used together with token or token_file.",
+			},
+			"google_auth_scopes": &schema.Schema{
+				Type:        schema.TypeList,
+				Optional:    true,
+				Description: "The OAuth scopes to request tokens for when google_auth is set. Defaults to https://www.googleapis.com/auth/cloud-platform.",
+				Elem: &schema.Schema{
+					Type: schema.TypeString,
+				},
+			},
+			"default_namespace": &schema.Schema{
+				Type:        schema.TypeString,
+				Optional:    true,
+				DefaultFunc: schema.EnvDefaultFunc("CDAP_NAMESPACE", defaultNamespace),
+				Description: "The namespace to use for resources that do not set one. Can also be set with the CDAP_NAMESPACE environment variable. Defaults to the default namespace.",
+			},
+			"gzip_uploads": &schema.Schema{
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Default:     false,
+				Description: "Whether to gzip compress artifact JARs while uploading them. Only enable this if the CDAP router accepts gzip encoded request bodies.",
+			},
+			"upload_content_type": &schema.Schema{
+				Type:         schema.TypeString,
+				Optional:     true,
+				Default:      "application/octet-stream",
+				ValidateFunc: validation.StringIsNotEmpty,
+				Description:  "The Content-Type of artifact JAR uploads. Only change it for gateways in front of the instance that reject uploads with the default of application/octet-stream.",
+			},
+			"path_base": &schema.Schema{
+				Type:        schema.TypeString,
+				Optional:    true,
+				Description: "The directory relative local file paths of resources, such as jar_binary_path and json_config_path, are resolved against. If not provided, they are resolved against the directory Terraform runs in, which is not necessarily the directory of the module. Set it to path.root or path.module to resolve paths against the configuration instead.",
+			},
+			"max_jar_bytes": &schema.Schema{
+				Type:         schema.TypeInt,
+				Optional:     true,
+				Default:      512 << 20,
+				ValidateFunc: validation.IntAtLeast(0),
+				Description:  "The maximum size in bytes of artifact JARs, to catch paths pointing at the wrong file before uploading it. 0 means no limit. Defaults to 512 MiB.",
+			},
+			"max_retries": &schema.Schema{
+				Type:         schema.TypeInt,
+				Optional:     true,
+				Default:      3,
+				ValidateFunc: validation.IntAtLeast(0),
+				Description:  "The maximum number of times to retry a call that failed with a transient error such as a 429, 502, 503, 504 or a refused connection. Waits requested by a Retry-After header are honored, calls asking to wait longer than retry_max_wait_seconds fail.",
+			},
+			"retry_max_wait_seconds": &schema.Schema{
+				Type:         schema.TypeInt,
+				Optional:     true,
+				Default:      30,
+				ValidateFunc: validation.IntAtLeast(1),
+				Description:  "The maximum number of seconds to wait between retries. The wait is random, up to a limit that starts at one second and doubles on every retry up to this value, so many resources retrying at once do not hit the instance at the same time.",
+			},
+			"max_concurrent_requests": &schema.Schema{
+				Type:         schema.TypeInt,
+				Optional:     true,
+				Default:      0,
+				ValidateFunc: validation.IntAtLeast(0),
+				Description:  "The maximum number of requests sent to the instance at the same time, across all resources and regardless of Terraform's -parallelism, to protect small instances. Retries wait for a free slot too. 0 means no limit.",
+			},
+			"credentials_file": &schema.Schema{
+				Type:        schema.TypeString,
+				Optional:    true,
+				Description: "The path to a Google service account key file used to download artifact JARs from gs:// URLs and, if google_auth is set, to authenticate to the instance. If not set, Application Default Credentials are used, for example through GOOGLE_APPLICATION_CREDENTIALS or the metadata server on GCE and Cloud Build.",
+			},
+			"http_timeout_seconds": &schema.Schema{
+				Type:         schema.TypeInt,
+				Optional:     true,
+				DefaultFunc:  schema.EnvDefaultFunc("CDAP_HTTP_TIMEOUT", 1800),
+				ValidateFunc: validation.IntAtLeast(0),
+				Description:  "The maximum number of seconds a single http call to the instance may take, including uploading artifact JARs. 0 means no limit. Create, update and delete timeouts of resources still apply and abort calls earlier if they are shorter. Can also be set with the CDAP_HTTP_TIMEOUT environment variable. Defaults to 30 minutes.",
+			},
+			"headers": &schema.Schema{
+				Type:        schema.TypeMap,
+				Optional:    true,
+				Description: "Additional HTTP headers to send with every request to the instance, such as API keys required by a gateway in front of it. Headers set by the provider itself take precedence. Values of headers whose names look like credentials are redacted in logs.",
+				Elem: &schema.Schema{
+					Type: schema.TypeString,
+				},
+			},
+			"user_agent_suffix": &schema.Schema{
+				Type:        schema.TypeString,
+				Optional:    true,
+				Description: "Text to append to the User-Agent of requests, such as the name of the team or pipeline running Terraform, to tell requests apart in the audit logs of the instance.",
+			},
+			"skip_connection_check": &schema.Schema{
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Default:     false,
+				Description: "Whether to skip checking that the instance is reachable when the provider is configured, for example to plan while offline.",
+			},
+			"proxy_url": &schema.Schema{
+				Type:         schema.TypeString,
+				Optional:     true,
+				ValidateFunc: validation.IsURLWithScheme([]string{"http", "https", "socks5"}),
+				Description:  "The URL of the proxy to send requests to the instance through. Takes precedence over the HTTPS_PROXY, HTTP_PROXY and NO_PROXY environment variables, which are used otherwise. JAR downloads of cdap_remote_artifact always use the environment variables.",
+			},
+			"insecure": &schema.Schema{
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Default:     false,
+				Description: "Whether to skip verification of the instance's TLS certificate. Only use this for testing.",
+			},
+			"ca_cert_file": &schema.Schema{
+				Type:        schema.TypeString,
+				Optional:    true,
+				Description: "The path to a PEM encoded CA bundle to verify the instance's TLS certificate with, for instances using self-signed certificates.",
+			},
+			"client_cert_file": &schema.Schema{
+				Type:         schema.TypeString,
+				Optional:     true,
+				RequiredWith: []string{"client_key_file"},
+				Description:  "The path to a PEM encoded client certificate to present to the instance, for routers enforcing mutual TLS. Must be set together with client_key_file.",
 			},
+			"client_key_file": &schema.Schema{
+				Type:         schema.TypeString,
+				Optional:     true,
+				RequiredWith: []string{"client_cert_file"},
+				Description:  "The path to the PEM encoded private key of the client certificate. Must be set together with client_cert_file.",
+			},
+		},
+		DataSourcesMap: map[string]*schema.Resource{
+			"cdap_app_config":            dataSourceAppConfig(),
+			"cdap_application":           dataSourceApplication(),
+			"cdap_artifact":              dataSourceArtifact(),
+			"cdap_artifact_config":       dataSourceArtifactConfig(),
+			"cdap_artifact_property":     dataSourceArtifactProperty(),
+			"cdap_artifacts":             dataSourceArtifacts(),
+			"cdap_config":                dataSourceConfig(),
+			"cdap_dataset":               dataSourceDataset(),
+			"cdap_lineage":               dataSourceLineage(),
+			"cdap_metrics":               dataSourceMetrics(),
+			"cdap_namespace":             dataSourceNamespace(),
+			"cdap_namespace_preferences": dataSourceNamespacePreferences(),
+			"cdap_plugin":                dataSourcePlugin(),
+			"cdap_service_endpoint":      dataSourceServiceEndpoint(),
 		},
-		ConfigureFunc: configureProvider,
 		ResourcesMap: map[string]*schema.Resource{
 			"cdap_application":           resourceApplication(),
+			"cdap_pipeline":              resourcePipeline(),
 			"cdap_streaming_program_run": resourceStreamingProgramRun(),
 			"cdap_gcs_artifact":          resourceGCSArtifact(),
 			"cdap_local_artifact":        resourceLocalArtifact(),
+			"cdap_local_artifact_bundle": resourceLocalArtifactBundle(),
+			"cdap_remote_artifact":       resourceRemoteArtifact(),
 			"cdap_namespace":             resourceNamespace(),
 			"cdap_namespace_preferences": resourceNamespacePreferences(),
 			"cdap_profile":               resourceProfile(),
+			"cdap_dataset":               resourceDataset(),
+			"cdap_dataset_truncate":      resourceDatasetTruncate(),
+			"cdap_preferences":           resourcePreferences(),
+			"cdap_secure_key":            resourceSecureKey(),
+			"cdap_schedule":              resourceSchedule(),
+			"cdap_program_run":           resourceProgramRun(),
+			"cdap_route_config":          resourceRouteConfig(),
+			"cdap_metadata":              resourceMetadata(),
+			"cdap_system_artifact_load":  resourceSystemArtifactLoad(),
+			"cdap_profile_assignment":    resourceProfileAssignment(),
+			"cdap_stream":                resourceStream(),
 		},
 	}
+	// The Terraform version is only known once the provider is configured.
+	p.ConfigureContextFunc = func(ctx context.Context, d *schema.ResourceData) (interface{}, diag.Diagnostics) {
+		config, err := configureProvider(ctx, d, p.TerraformVersion)
+		if err != nil {
+			return nil, diag.FromErr(err)
+		}
+		return config, nil
+	}
+	return p
 }
 
 // Config provides service configuration for service clients.
 type Config struct {
-	host          string
-	httpClient    *http.Client
-	storageClient *storage.Client
+	host              string
+	token             string
+	defaultNamespace  string
+	httpClient        *apiClient
+	storageClient     *storage.Client
+	gzipUploads       bool
+	uploadContentType string
+	credentialsFile   string
+	maxJarBytes       int64
+	pathBase          string
 }
 
-func configureProvider(d *schema.ResourceData) (interface{}, error) {
-	ctx := context.Background()
+func configureProvider(ctx context.Context, d *schema.ResourceData, terraformVersion string) (*Config, error) {
+	// Unlike ctx, the stop context lives as long as the provider. It is
+	// cancelled when Terraform is interrupted, for example by Ctrl-C.
+	stopCtx, ok := schema.StopContext(ctx)
+	if !ok {
+		stopCtx = context.Background()
+	}
+
+	token, err := readToken(d)
+	if err != nil {
+		return nil, err
+	}
+
+	tlsConfig, err := newTLSConfig(d)
+	if err != nil {
+		return nil, err
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+	// Use the proxy environment variables unless proxy_url overrides them.
+	transport.Proxy = http.ProxyFromEnvironment
+	if p, ok := d.GetOk("proxy_url"); ok {
+		proxyURL, err := url.Parse(p.(string))
+		if err != nil {
+			return nil, fmt.Errorf("invalid proxy_url: %v", err)
+		}
+		transport.Proxy = http.ProxyURL(proxyURL)
+	}
 
-	httpClient := &http.Client{}
-	if token, ok := d.GetOk("token"); ok {
-		httpClient = oauth2.NewClient(ctx, oauth2.StaticTokenSource(&oauth2.Token{
-			AccessToken: token.(string),
+	httpClient := &http.Client{Transport: transport}
+	// The oauth2 client wraps the transport of the client in the context,
+	// which is also used to fetch Google tokens.
+	oauthCtx := context.WithValue(stopCtx, oauth2.HTTPClient, httpClient)
+	var tokenSource oauth2.TokenSource
+	switch {
+	case d.Get("google_auth").(bool):
+		if token != "" {
+			return nil, errors.New("google_auth cannot be used together with token (or the CDAP_TOKEN environment variable) or token_file")
+		}
+		tokenSource, err = googleTokenSource(oauthCtx, d)
+		if err != nil {
+			return nil, err
+		}
+	case token != "":
+		tokenSource = oauth2.StaticTokenSource(&oauth2.Token{
+			AccessToken: token,
 			TokenType:   "Bearer",
-		}))
+		})
+	}
+	if tokenSource != nil {
+		httpClient = oauth2.NewClient(oauthCtx, tokenSource)
 	}
-	httpClient.Timeout = 30 * time.Minute
+	httpClient.Timeout = time.Duration(d.Get("http_timeout_seconds").(int)) * time.Second
 
-	storageClient, err := storage.NewClient(ctx, option.WithScopes(storage.ScopeReadOnly), option.WithoutAuthentication())
+	client := &apiClient{
+		Client:       httpClient,
+		maxRetries:   d.Get("max_retries").(int),
+		retryMaxWait: time.Duration(d.Get("retry_max_wait_seconds").(int)) * time.Second,
+		headers:      make(map[string]string),
+		userAgent:    userAgent(terraformVersion, d.Get("user_agent_suffix").(string)),
+		stopCtx:      stopCtx,
+	}
+	for k, v := range d.Get("headers").(map[string]interface{}) {
+		client.headers[k] = v.(string)
+	}
+	if n := d.Get("max_concurrent_requests").(int); n > 0 {
+		client.inFlight = make(chan struct{}, n)
+	}
+
+	storageClient, err := storage.NewClient(stopCtx, option.WithScopes(storage.ScopeReadOnly), option.WithoutAuthentication())
 	if err != nil {
 		return nil, err
 	}
 
-	return &Config{
-		host:          d.Get("host").(string),
-		httpClient:    httpClient,
-		storageClient: storageClient,
-	}, nil
+	host := d.Get("host").(string)
+	if err := validateHost(host); err != nil {
+		return nil, err
+	}
+	if prefix := d.Get("api_prefix").(string); prefix != "" && host != "" {
+		host = urlJoin(host, prefix)
+	}
+
+	config := &Config{
+		host:              host,
+		token:             token,
+		defaultNamespace:  d.Get("default_namespace").(string),
+		httpClient:        client,
+		storageClient:     storageClient,
+		gzipUploads:       d.Get("gzip_uploads").(bool),
+		uploadContentType: d.Get("upload_content_type").(string),
+		credentialsFile:   d.Get("credentials_file").(string),
+		maxJarBytes:       int64(d.Get("max_jar_bytes").(int)),
+		pathBase:          d.Get("path_base").(string),
+	}
+
+	// The host may only be known after other resources are created.
+	if !d.Get("skip_connection_check").(bool) && config.host != "" {
+		if err := checkConnection(ctx, config); err != nil {
+			return nil, err
+		}
+	}
+	return config, nil
+}
+
+// userAgent returns the User-Agent of requests, which identifies the provider
+// in the audit logs of the instance.
+func userAgent(terraformVersion, suffix string) string {
+	if terraformVersion == "" {
+		// Terraform 0.12 and later always report their version.
+		terraformVersion = "unknown"
+	}
+	ua := fmt.Sprintf("terraform-provider-cdap/%s (terraform/%s)", Version, terraformVersion)
+	if suffix != "" {
+		ua += " " + suffix
+	}
+	return ua
+}
+
+// validateHost checks that the host is an http or https URL. An empty host is
+// allowed, since it may only be known after other resources are created.
+func validateHost(host string) error {
+	if host == "" {
+		return nil
+	}
+	u, err := url.Parse(host)
+	if err != nil {
+		return fmt.Errorf("invalid host %q: %v", host, err)
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("invalid host %q: want a URL such as https://cdap.example.com:11015", host)
+	}
+	return nil
+}
+
+// connectionCheckTimeout is how long the check at configure time waits for the instance.
+const connectionCheckTimeout = 15 * time.Second
+
+// checkConnection lists the namespaces once so a wrong host or token is
+// reported as a single error instead of failing every resource.
+func checkConnection(ctx context.Context, config *Config) error {
+	ctx, cancel := context.WithTimeout(ctx, connectionCheckTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, urlJoin(config.host, "/v3/namespaces"), nil)
+	if err != nil {
+		return fmt.Errorf("cannot reach CDAP at %s: %v", config.host, err)
+	}
+	config.httpClient.setHeaders(req)
+	if _, err := doHTTPCall(config.httpClient.Client, req); err != nil {
+		return fmt.Errorf("cannot reach CDAP at %s: %v (set skip_connection_check to plan without an instance)", config.host, err)
+	}
+	return nil
+}
+
+// newTLSConfig returns the TLS config for calls to the instance.
+func newTLSConfig(d *schema.ResourceData) (*tls.Config, error) {
+	tlsConfig := &tls.Config{}
+
+	if caFile, ok := d.GetOk("ca_cert_file"); ok {
+		b, err := ioutil.ReadFile(caFile.(string))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read ca_cert_file: %v", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(b) {
+			return nil, fmt.Errorf("ca_cert_file %q contains no PEM encoded certificates", caFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	certFile, hasCert := d.GetOk("client_cert_file")
+	keyFile, hasKey := d.GetOk("client_key_file")
+	if hasCert != hasKey {
+		return nil, errors.New("client_cert_file and client_key_file must be set together")
+	}
+	if hasCert {
+		cert, err := tls.LoadX509KeyPair(certFile.(string), keyFile.(string))
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate: %v", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	if d.Get("insecure").(bool) {
+		log.Printf("[WARN] insecure is set, the TLS certificate of the instance will not be verified")
+		if tlsConfig.RootCAs != nil {
+			log.Printf("[WARN] ca_cert_file has no effect while insecure is set")
+		}
+		tlsConfig.InsecureSkipVerify = true
+	}
+	return tlsConfig, nil
+}
+
+// defaultGoogleAuthScope is the scope Cloud Data Fusion instances accept tokens for.
+const defaultGoogleAuthScope = "https://www.googleapis.com/auth/cloud-platform"
+
+// googleTokenSource returns a source of Google access tokens for the
+// credentials in credentials_file, or Application Default Credentials. The
+// tokens are cached and refreshed shortly before they expire.
+func googleTokenSource(ctx context.Context, d *schema.ResourceData) (oauth2.TokenSource, error) {
+	var scopes []string
+	for _, s := range d.Get("google_auth_scopes").([]interface{}) {
+		scopes = append(scopes, s.(string))
+	}
+	if len(scopes) == 0 {
+		scopes = []string{defaultGoogleAuthScope}
+	}
+
+	if path, ok := d.GetOk("credentials_file"); ok {
+		b, err := ioutil.ReadFile(path.(string))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read credentials_file: %v", err)
+		}
+		creds, err := google.CredentialsFromJSON(ctx, b, scopes...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load credentials_file: %v", err)
+		}
+		return creds.TokenSource, nil
+	}
+	creds, err := google.FindDefaultCredentials(ctx, scopes...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find Application Default Credentials for google_auth, set credentials_file or GOOGLE_APPLICATION_CREDENTIALS: %v", err)
+	}
+	return creds.TokenSource, nil
+}
+
+// readToken returns the token from either the token or the token_file field.
+func readToken(d *schema.ResourceData) (string, error) {
+	token := d.Get("token").(string)
+	tokenFile, ok := d.GetOk("token_file")
+	if !ok {
+		return token, nil
+	}
+	if token != "" {
+		return "", errors.New("only one of token (or the CDAP_TOKEN environment variable) and token_file can be set")
+	}
+	b, err := ioutil.ReadFile(tokenFile.(string))
+	if err != nil {
+		return "", fmt.Errorf("failed to read token_file: %v", err)
+	}
+	return strings.TrimSpace(string(b)), nil
+}
+
+// setDefaultNamespace sets the namespace of the resource to the provider's
+// default namespace if it was not configured, and returns an error if neither
+// is set.
+func setDefaultNamespace(d *schema.ResourceData, config *Config) error {
+	if _, ok := d.GetOk("namespace"); ok {
+		return nil
+	}
+	// An empty segment would be dropped by urlJoin, sending requests to the
+	// wrong endpoint.
+	if config.defaultNamespace == "" {
+		return errors.New("namespace must be set on the resource or as the provider's default_namespace")
+	}
+	return d.Set("namespace", config.defaultNamespace)
 }
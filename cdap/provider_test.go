@@ -0,0 +1,57 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cdap
+
+import (
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+func TestSetDefaultNamespace(t *testing.T) {
+	tests := []struct {
+		name             string
+		namespace        string
+		defaultNamespace string
+		want             string
+		wantErr          bool
+	}{
+		{name: "resource namespace", namespace: "team", defaultNamespace: "default", want: "team"},
+		{name: "resource namespace without default", namespace: "team", want: "team"},
+		{name: "provider default", defaultNamespace: "shared", want: "shared"},
+		{name: "neither", wantErr: true},
+	}
+	for _, tt := range tests {
+		raw := map[string]interface{}{"name": "example"}
+		if tt.namespace != "" {
+			raw["namespace"] = tt.namespace
+		}
+		d := schema.TestResourceDataRaw(t, resourceSecureKey().Schema, raw)
+		err := setDefaultNamespace(d, &Config{defaultNamespace: tt.defaultNamespace})
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("%s: setDefaultNamespace() set namespace %q, want error", tt.name, d.Get("namespace"))
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: setDefaultNamespace() returned error: %v", tt.name, err)
+			continue
+		}
+		if got := d.Get("namespace").(string); got != tt.want {
+			t.Errorf("%s: namespace = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
@@ -15,8 +15,16 @@
 package cdap
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
 	"net/http"
+	"regexp"
+	"sort"
 	"strings"
 
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
@@ -29,18 +37,19 @@ func resourceApplication() *schema.Resource {
 	return &schema.Resource{
 		Create: resourceApplicationCreate,
 		Read:   resourceApplicationRead,
+		Update: resourceApplicationUpdate,
 		Delete: resourceApplicationDelete,
 		Exists: resourceApplicationExists,
 
+		CustomizeDiff: resourceApplicationCustomizeDiff,
+
 		Schema: map[string]*schema.Schema{
 			"namespace": {
 				Type:        schema.TypeString,
 				Optional:    true,
+				Computed:    true,
 				ForceNew:    true,
-				Description: "The name of the namespace in which this resource belongs. If not provided, the default namespace is used.",
-				DefaultFunc: func() (interface{}, error) {
-					return defaultNamespace, nil
-				},
+				Description: "The name of the namespace in which this resource belongs. If not provided, the provider's default_namespace is used.",
 			},
 			"name": {
 				Type:        schema.TypeString,
@@ -50,40 +59,427 @@ func resourceApplication() *schema.Resource {
 			},
 			"spec": {
 				Type:         schema.TypeString,
-				Description:  "The full contents of the exported pipeline JSON spec.",
-				Required:     true,
+				Description:  "The full contents of the exported pipeline JSON spec. Exactly one of spec or artifact must be set.",
+				Optional:     true,
 				ForceNew:     true,
-				ValidateFunc: validation.StringIsJSON,
+				ExactlyOneOf: []string{"spec", "artifact"},
+				StateFunc: func(v interface{}) string {
+					json, _ := structure.NormalizeJsonString(v)
+					return json
+				},
+			},
+			"artifact": {
+				Type:        schema.TypeList,
+				Description: "The artifact to create the application from.",
+				Optional:    true,
+				ForceNew:    true,
+				MaxItems:    1,
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"name": {
+							Type:        schema.TypeString,
+							Required:    true,
+							ForceNew:    true,
+							Description: "The name of the artifact.",
+						},
+						"version": {
+							Type:        schema.TypeString,
+							Required:    true,
+							ForceNew:    true,
+							Description: "The version of the artifact.",
+						},
+						"scope": {
+							Type:         schema.TypeString,
+							Optional:     true,
+							ForceNew:     true,
+							Default:      "user",
+							ValidateFunc: validation.StringInSlice([]string{"user", "system"}, true),
+							Description:  "The scope of the artifact, either user or system.",
+						},
+					},
+				},
+			},
+			"config": {
+				Type:          schema.TypeString,
+				Description:   "The application config as a JSON string. Changing it redeploys the application in place.",
+				Optional:      true,
+				ConflictsWith: []string{"spec", "config_path"},
 				StateFunc: func(v interface{}) string {
 					json, _ := structure.NormalizeJsonString(v)
 					return json
 				},
 			},
+			"config_path": {
+				Type:          schema.TypeString,
+				Description:   "The local path to the application config JSON. Changing the path or the contents of the file redeploys the application in place.",
+				Optional:      true,
+				ConflictsWith: []string{"spec", "config"},
+			},
+			"config_sha256": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "The hex encoded SHA-256 checksum of the file at config_path the application was deployed with. The application is redeployed when the file no longer matches it.",
+			},
+			"config_vars": configVarsSchema(),
+			"app_version": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Description: "The version of the application, for CDAP versions that support application versions. Changing it deploys a new version next to the existing ones instead of overwriting them, for example for blue/green rollouts. If not set, deploys overwrite the application.",
+			},
+			"deployed_versions": {
+				Type:        schema.TypeList,
+				Computed:    true,
+				Description: "The application versions deployed by this resource. All of them are deleted with the resource.",
+				Elem: &schema.Schema{
+					Type: schema.TypeString,
+				},
+			},
 		},
 	}
 }
 
 func resourceApplicationCreate(d *schema.ResourceData, m interface{}) error {
+	config := m.(*Config)
+	if err := setDefaultNamespace(d, config); err != nil {
+		return err
+	}
+	if err := deployApplication(d, config); err != nil {
+		return err
+	}
+
+	d.SetId(d.Get("name").(string))
+	if err := setAppConfigHash(d, config); err != nil {
+		return err
+	}
+	return recordAppVersion(d)
+}
+
+func resourceApplicationUpdate(d *schema.ResourceData, m interface{}) error {
+	config := m.(*Config)
+	// Deploying on top of an existing application updates it in place, while
+	// deploying a new app_version leaves the previous versions running.
+	if err := deployApplication(d, config); err != nil {
+		return err
+	}
+	if err := setAppConfigHash(d, config); err != nil {
+		return err
+	}
+	return recordAppVersion(d)
+}
+
+// setAppConfigHash records the checksum of the file at config_path, or clears
+// it if the config is not read from a file.
+func setAppConfigHash(d *schema.ResourceData, config *Config) error {
+	p, ok := d.GetOk("config_path")
+	if !ok {
+		return d.Set("config_sha256", "")
+	}
+	sum, err := fileSHA256(localPath(config, p.(string)))
+	if err != nil {
+		return err
+	}
+	return d.Set("config_sha256", sum)
+}
+
+// recordAppVersion adds the deployed app_version to deployed_versions.
+func recordAppVersion(d *schema.ResourceData) error {
+	v, ok := d.GetOk("app_version")
+	if !ok {
+		return nil
+	}
+	var versions []string
+	for _, dv := range d.Get("deployed_versions").([]interface{}) {
+		if dv.(string) == v.(string) {
+			return nil
+		}
+		versions = append(versions, dv.(string))
+	}
+	return d.Set("deployed_versions", append(versions, v.(string)))
+}
+
+// appRequest is the body used to deploy an application from an artifact.
+type appRequest struct {
+	Artifact *appArtifact    `json:"artifact"`
+	Config   json.RawMessage `json:"config,omitempty"`
+}
+
+type appArtifact struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+	Scope   string `json:"scope"`
+}
+
+// appDetail is the subset of the CDAP application detail used by this provider.
+type appDetail struct {
+	Name          string        `json:"name"`
+	AppVersion    string        `json:"appVersion"`
+	Description   string        `json:"description"`
+	Configuration string        `json:"configuration"`
+	Artifact      *appArtifact  `json:"artifact"`
+	Programs      []*appProgram `json:"programs"`
+}
+
+type appProgram struct {
+	Type        string `json:"type"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+func deployApplication(d *schema.ResourceData, config *Config) error {
+	addr := urlJoin(config.host, "/v3/namespaces", d.Get("namespace").(string), "/apps", d.Get("name").(string))
+	method := http.MethodPut
+	if v, ok := d.GetOk("app_version"); ok {
+		addr = urlJoin(addr, "/versions", v.(string), "/create")
+		method = http.MethodPost
+	}
+
+	var body io.Reader
+	if spec, ok := d.GetOk("spec"); ok {
+		s, err := substituteConfigVars(spec.(string), configVars(d))
+		if err != nil {
+			return fmt.Errorf("spec: %v", err)
+		}
+		body = strings.NewReader(s)
+	} else {
+		b, err := appRequestBody(d, config)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(b)
+	}
+
+	req, err := http.NewRequest(method, addr, body)
+	if err != nil {
+		return err
+	}
+
+	_, err = httpCall(config.httpClient, req)
+	return err
+}
+
+func appRequestBody(d *schema.ResourceData, config *Config) ([]byte, error) {
+	a := d.Get("artifact").([]interface{})[0].(map[string]interface{})
+	ar := &appRequest{
+		Artifact: &appArtifact{
+			Name:    a["name"].(string),
+			Version: a["version"].(string),
+			Scope:   strings.ToUpper(a["scope"].(string)),
+		},
+	}
+
+	if c, ok := d.GetOk("config"); ok {
+		conf, err := substituteConfigVars(c.(string), configVars(d))
+		if err != nil {
+			return nil, fmt.Errorf("config: %v", err)
+		}
+		ar.Config = json.RawMessage(conf)
+	} else if p, ok := d.GetOk("config_path"); ok {
+		path := localPath(config, p.(string))
+		b, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		conf, err := substituteConfigVars(string(b), configVars(d))
+		if err != nil {
+			return nil, fmt.Errorf("application config %q: %v", path, err)
+		}
+		if !json.Valid([]byte(conf)) {
+			return nil, fmt.Errorf("application config %q is not valid JSON", path)
+		}
+		ar.Config = json.RawMessage(conf)
+	}
+
+	return json.Marshal(ar)
+}
+
+func resourceApplicationRead(d *schema.ResourceData, m interface{}) error {
 	config := m.(*Config)
 	name := d.Get("name").(string)
 	addr := urlJoin(config.host, "/v3/namespaces", d.Get("namespace").(string), "/apps", name)
+	if v, ok := d.GetOk("app_version"); ok {
+		addr = urlJoin(addr, "/versions", v.(string))
+	}
 
-	body := strings.NewReader(d.Get("spec").(string))
-
-	req, err := http.NewRequest(http.MethodPut, addr, body)
+	req, err := http.NewRequest(http.MethodGet, addr, nil)
 	if err != nil {
 		return err
 	}
 
-	if _, err := httpCall(config.httpClient, req); err != nil {
+	b, err := httpCall(config.httpClient, req)
+	if isNotFound(err) {
+		log.Printf("application %q not found, removing from state", name)
+		d.SetId("")
+		return nil
+	}
+	if err != nil {
+		return permissionError(err, fmt.Sprintf("read application %q", name))
+	}
+
+	// Applications deployed from a spec are not refreshed, since CDAP does not return the
+	// spec in the shape it was deployed with.
+	if _, ok := d.GetOk("artifact"); !ok {
+		return nil
+	}
+
+	detail := new(appDetail)
+	if err := json.Unmarshal(b, detail); err != nil {
 		return err
 	}
+	if detail.Artifact != nil {
+		d.Set("artifact", []map[string]interface{}{{
+			"name":    detail.Artifact.Name,
+			"version": detail.Artifact.Version,
+			"scope":   strings.ToLower(detail.Artifact.Scope),
+		}})
+	}
+	// Only an inline config can be compared to the deployed one.
+	if c, ok := d.GetOk("config"); ok && detail.Configuration != "" {
+		conf, err := structure.NormalizeJsonString(detail.Configuration)
+		if err != nil {
+			return fmt.Errorf("failed to parse configuration of application %q: %v", name, err)
+		}
+		// The config in state has the placeholders, so it is compared after
+		// substitution and only replaced if the deployed config differs.
+		if s, err := substituteConfigVars(c.(string), configVars(d)); err == nil {
+			if want, err := structure.NormalizeJsonString(s); err == nil && want == conf {
+				return nil
+			}
+		}
+		d.Set("config", escapeConfigVars(conf, configVars(d)))
+	}
+	return nil
+}
 
-	d.SetId(name)
+// resourceApplicationCustomizeDiff checks that spec and config are valid JSON
+// once config_vars are substituted, since placeholders may stand for values
+// that are not strings.
+func resourceApplicationCustomizeDiff(ctx context.Context, d *schema.ResourceDiff, m interface{}) error {
+	if err := appConfigHashDiff(d, m); err != nil {
+		return err
+	}
+	if !d.NewValueKnown("config_vars") {
+		return nil
+	}
+	vars := d.Get("config_vars").(map[string]interface{})
+	for _, k := range []string{"spec", "config"} {
+		if !d.NewValueKnown(k) {
+			continue
+		}
+		if err := validateConfigJSON(k, d.Get(k).(string), vars); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
-func resourceApplicationRead(d *schema.ResourceData, m interface{}) error {
+// appConfigHashDiff plans a redeploy when the file at config_path changed
+// since the application was deployed, as only the path is in the config.
+func appConfigHashDiff(d *schema.ResourceDiff, m interface{}) error {
+	if d.Id() == "" || !d.NewValueKnown("config_path") {
+		return nil
+	}
+	p := d.Get("config_path").(string)
+	if p == "" {
+		if d.Get("config_sha256").(string) != "" {
+			return d.SetNew("config_sha256", "")
+		}
+		return nil
+	}
+	config, _ := m.(*Config)
+	sum, err := fileSHA256(localPath(config, p))
+	if err != nil {
+		// The file may be generated later in the apply.
+		log.Printf("[DEBUG] skipping plan time checksum of %q: %v", p, err)
+		if d.HasChange("config_path") {
+			return d.SetNewComputed("config_sha256")
+		}
+		return nil
+	}
+	if sum == d.Get("config_sha256").(string) {
+		return nil
+	}
+	return d.SetNew("config_sha256", sum)
+}
+
+func configVarsSchema() *schema.Schema {
+	return &schema.Schema{
+		Type:     schema.TypeMap,
+		Optional: true,
+		Elem: &schema.Schema{
+			Type: schema.TypeString,
+		},
+		Description: "Variables substituted into the config before it is parsed and deployed, so one config can be reused across environments. Each ${name} is replaced as is by the value of name, which must be set, so placeholders inside JSON strings are quoted while others can stand for numbers or booleans. Write $${ for a literal ${, such as the start of a CDAP macro. Substitution only happens if config_vars is set.",
+	}
+}
+
+// configVars returns the variables to substitute, or nil if config_vars is
+// not set.
+func configVars(d *schema.ResourceData) map[string]interface{} {
+	vars, _ := d.Get("config_vars").(map[string]interface{})
+	if len(vars) == 0 {
+		return nil
+	}
+	return vars
+}
+
+// configVarRE matches an escaped $${ or a ${name} placeholder.
+var configVarRE = regexp.MustCompile(`\$\$\{|\$\{([^}]*)\}`)
+
+// substituteConfigVars replaces the ${name} placeholders of s with the values
+// of vars, and $${ with ${. It returns an error listing the placeholders that
+// have no value. If vars is empty, s is returned unchanged, so configs with
+// CDAP macros keep working without escapes.
+func substituteConfigVars(s string, vars map[string]interface{}) (string, error) {
+	if len(vars) == 0 {
+		return s, nil
+	}
+	missing := make(map[string]bool)
+	out := configVarRE.ReplaceAllStringFunc(s, func(match string) string {
+		if match == "$${" {
+			return "${"
+		}
+		name := match[2 : len(match)-1]
+		v, ok := vars[name]
+		if !ok {
+			missing[name] = true
+			return match
+		}
+		return v.(string)
+	})
+	if len(missing) > 0 {
+		var names []string
+		for name := range missing {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return "", fmt.Errorf("no config_vars for placeholders %q, write $${ for a literal ${", names)
+	}
+	return out, nil
+}
+
+// escapeConfigVars escapes each ${ of s as $${, so a deployed config written
+// back to state substitutes to itself, for example when it has CDAP macros.
+// Like substituteConfigVars, it returns s unchanged if vars is empty.
+func escapeConfigVars(s string, vars map[string]interface{}) string {
+	if len(vars) == 0 {
+		return s
+	}
+	return strings.ReplaceAll(s, "${", "$${")
+}
+
+// validateConfigJSON checks that the value of attribute k is valid JSON once
+// vars are substituted.
+func validateConfigJSON(k, v string, vars map[string]interface{}) error {
+	if v == "" {
+		return nil
+	}
+	s, err := substituteConfigVars(v, vars)
+	if err != nil {
+		return fmt.Errorf("%s: %v", k, err)
+	}
+	if !json.Valid([]byte(s)) {
+		return fmt.Errorf("%s is not valid JSON after substituting config_vars", k)
+	}
 	return nil
 }
 
@@ -92,6 +488,19 @@ func resourceApplicationDelete(d *schema.ResourceData, m interface{}) error {
 	name := d.Get("name").(string)
 	addr := urlJoin(config.host, "/v3/namespaces", d.Get("namespace").(string), "/apps", name)
 
+	if versions := d.Get("deployed_versions").([]interface{}); len(versions) > 0 {
+		for _, v := range versions {
+			req, err := http.NewRequest(http.MethodDelete, urlJoin(addr, "/versions", v.(string)), nil)
+			if err != nil {
+				return err
+			}
+			if _, err := httpCall(config.httpClient, req); err != nil && !isNotFound(err) {
+				return fmt.Errorf("failed to delete version %q of application %q: %v", v, name, err)
+			}
+		}
+		return nil
+	}
+
 	req, err := http.NewRequest(http.MethodDelete, addr, nil)
 	if err != nil {
 		return err
@@ -0,0 +1,236 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cdap
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+// https://docs.cdap.io/cdap/current/en/reference-manual/http-restful-api/dataset.html
+func resourceDataset() *schema.Resource {
+	return &schema.Resource{
+		Create: resourceDatasetCreate,
+		Read:   resourceDatasetRead,
+		Update: resourceDatasetUpdate,
+		Delete: resourceDatasetDelete,
+		Exists: resourceDatasetExists,
+
+		Schema: map[string]*schema.Schema{
+			"name": {
+				Type:        schema.TypeString,
+				Required:    true,
+				ForceNew:    true,
+				Description: "The name of the dataset.",
+			},
+			"namespace": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Computed:    true,
+				ForceNew:    true,
+				Description: "The name of the namespace in which this resource belongs. If not provided, the provider's default_namespace is used.",
+			},
+			"type": {
+				Type:        schema.TypeString,
+				Required:    true,
+				ForceNew:    true,
+				Description: "The type of the dataset, such as table or fileSet.",
+			},
+			"description": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				ForceNew:    true,
+				Description: "A description of the dataset.",
+			},
+			"properties": {
+				Type:        schema.TypeMap,
+				Optional:    true,
+				Description: "The properties of the dataset. Changing them updates the dataset in place, as far as the dataset type allows it.",
+				Elem: &schema.Schema{
+					Type: schema.TypeString,
+				},
+			},
+		},
+	}
+}
+
+// datasetInstanceConfig is the body used to create a dataset.
+type datasetInstanceConfig struct {
+	TypeName    string            `json:"typeName"`
+	Properties  map[string]string `json:"properties"`
+	Description string            `json:"description,omitempty"`
+}
+
+// datasetMeta is the subset of the CDAP dataset detail used by this provider.
+type datasetMeta struct {
+	Spec struct {
+		Name string `json:"name"`
+		Type string `json:"type"`
+		// OriginalProperties are the properties the dataset was created with,
+		// without the ones added by the dataset type.
+		OriginalProperties map[string]string `json:"originalProperties"`
+		Description        string            `json:"description"`
+	} `json:"spec"`
+}
+
+func resourceDatasetCreate(d *schema.ResourceData, m interface{}) error {
+	config := m.(*Config)
+	if err := setDefaultNamespace(d, config); err != nil {
+		return err
+	}
+	name := d.Get("name").(string)
+	addr := urlJoin(config.host, "/v3/namespaces", d.Get("namespace").(string), "/data/datasets", name)
+
+	b, err := json.Marshal(&datasetInstanceConfig{
+		TypeName:    d.Get("type").(string),
+		Properties:  datasetProperties(d),
+		Description: d.Get("description").(string),
+	})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPut, addr, bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	if _, err := httpCall(config.httpClient, req); err != nil {
+		return err
+	}
+
+	d.SetId(name)
+	return nil
+}
+
+func datasetProperties(d *schema.ResourceData) map[string]string {
+	props := make(map[string]string)
+	for k, v := range d.Get("properties").(map[string]interface{}) {
+		props[k] = v.(string)
+	}
+	return props
+}
+
+func resourceDatasetRead(d *schema.ResourceData, m interface{}) error {
+	config := m.(*Config)
+	name := d.Get("name").(string)
+	addr := urlJoin(config.host, "/v3/namespaces", d.Get("namespace").(string), "/data/datasets", name)
+
+	req, err := http.NewRequest(http.MethodGet, addr, nil)
+	if err != nil {
+		return err
+	}
+
+	b, err := httpCall(config.httpClient, req)
+	if isNotFound(err) {
+		log.Printf("dataset %q not found, removing from state", name)
+		d.SetId("")
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	meta := new(datasetMeta)
+	if err := json.Unmarshal(b, meta); err != nil {
+		return err
+	}
+
+	d.Set("type", meta.Spec.Type)
+	d.Set("description", meta.Spec.Description)
+	d.Set("properties", meta.Spec.OriginalProperties)
+	return nil
+}
+
+func resourceDatasetUpdate(d *schema.ResourceData, m interface{}) error {
+	config := m.(*Config)
+	addr := urlJoin(config.host, "/v3/namespaces", d.Get("namespace").(string), "/data/datasets", d.Get("name").(string), "/properties")
+
+	b, err := json.Marshal(datasetProperties(d))
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPut, addr, bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	_, err = httpCall(config.httpClient, req)
+	return err
+}
+
+func resourceDatasetDelete(d *schema.ResourceData, m interface{}) error {
+	config := m.(*Config)
+	name := d.Get("name").(string)
+	addr := urlJoin(config.host, "/v3/namespaces", d.Get("namespace").(string), "/data/datasets", name)
+
+	req, err := http.NewRequest(http.MethodDelete, addr, nil)
+	if err != nil {
+		return err
+	}
+	_, err = httpCall(config.httpClient, req)
+
+	// CDAP refuses to delete datasets that are used by running programs.
+	var httpErr *httpError
+	if errors.As(err, &httpErr) && httpErr.code == http.StatusConflict {
+		return fmt.Errorf("failed to delete dataset %q, stop the programs using it and try again: %v", name, err)
+	}
+	return err
+}
+
+func resourceDatasetExists(d *schema.ResourceData, m interface{}) (bool, error) {
+	config := m.(*Config)
+	name := d.Get("name").(string)
+
+	namespace := d.Get("namespace").(string)
+	if exists, err := namespaceExists(config, namespace); err != nil {
+		return false, fmt.Errorf("failed to check for existence of namespace %q: %v", namespace, err)
+	} else if !exists {
+		return false, nil
+	}
+
+	addr := urlJoin(config.host, "/v3/namespaces", namespace, "/data/datasets")
+
+	req, err := http.NewRequest(http.MethodGet, addr, nil)
+	if err != nil {
+		return false, err
+	}
+
+	b, err := httpCall(config.httpClient, req)
+	if err != nil {
+		return false, err
+	}
+
+	type dataset struct {
+		Name string `json:"name"`
+	}
+
+	var datasets []dataset
+	if err := json.Unmarshal(b, &datasets); err != nil {
+		return false, err
+	}
+
+	for _, ds := range datasets {
+		if ds.Name == name {
+			return true, nil
+		}
+	}
+	return false, nil
+}
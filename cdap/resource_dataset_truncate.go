@@ -0,0 +1,92 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cdap
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+// resourceDatasetTruncate deletes all data of a dataset, such as to reset a
+// staging table while provisioning. Like a null_resource, it does nothing on
+// delete and is re-run when its triggers change.
+// https://docs.cdap.io/cdap/current/en/reference-manual/http-restful-api/dataset.html
+func resourceDatasetTruncate() *schema.Resource {
+	return &schema.Resource{
+		Create: resourceDatasetTruncateCreate,
+		Read:   resourceDatasetTruncateRead,
+		Delete: resourceDatasetTruncateDelete,
+
+		Schema: map[string]*schema.Schema{
+			"namespace": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Computed:    true,
+				ForceNew:    true,
+				Description: "The name of the namespace in which the dataset belongs. If not provided, the provider's default_namespace is used.",
+			},
+			"dataset": {
+				Type:        schema.TypeString,
+				Required:    true,
+				ForceNew:    true,
+				Description: "The name of the dataset to truncate. All of its data is deleted.",
+			},
+			"triggers": {
+				Type:        schema.TypeMap,
+				Optional:    true,
+				ForceNew:    true,
+				Description: "Arbitrary values that truncate the dataset again when changed.",
+				Elem: &schema.Schema{
+					Type: schema.TypeString,
+				},
+			},
+		},
+	}
+}
+
+func resourceDatasetTruncateCreate(d *schema.ResourceData, m interface{}) error {
+	config := m.(*Config)
+	if err := setDefaultNamespace(d, config); err != nil {
+		return err
+	}
+	name := d.Get("dataset").(string)
+	addr := urlJoin(config.host, "/v3/namespaces", d.Get("namespace").(string), "/data/datasets", name, "/admin/truncate")
+
+	req, err := http.NewRequest(http.MethodPost, addr, nil)
+	if err != nil {
+		return err
+	}
+	if _, err := httpCall(config.httpClient, req); err != nil {
+		if isNotFound(err) {
+			return fmt.Errorf("failed to truncate dataset %q, it does not exist in namespace %q: %v", name, d.Get("namespace"), err)
+		}
+		return err
+	}
+
+	d.SetId(resource.UniqueId())
+	return nil
+}
+
+func resourceDatasetTruncateRead(d *schema.ResourceData, m interface{}) error {
+	return nil
+}
+
+func resourceDatasetTruncateDelete(d *schema.ResourceData, m interface{}) error {
+	// Truncated data cannot be restored, so there is nothing to undo.
+	return nil
+}
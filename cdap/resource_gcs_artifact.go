@@ -15,8 +15,8 @@
 package cdap
 
 import (
+	"bytes"
 	"context"
-	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"regexp"
@@ -47,11 +47,9 @@ func resourceGCSArtifact() *schema.Resource {
 			"namespace": {
 				Type:        schema.TypeString,
 				Optional:    true,
+				Computed:    true,
 				ForceNew:    true,
-				Description: "The name of the namespace in which this resource belongs. If not provided, the default namespace is used.",
-				DefaultFunc: func() (interface{}, error) {
-					return defaultNamespace, nil
-				},
+				Description: "The name of the namespace in which this resource belongs. If not provided, the provider's default_namespace is used.",
 			},
 			// Technically, we could omit the version in the API call because CDAP will infer the
 			// version from the jar. However, forcing the user to specify the version makes dealing
@@ -74,40 +72,58 @@ func resourceGCSArtifact() *schema.Resource {
 				ForceNew:    true,
 				Description: "The GCS path to the JSON config of the artifact.",
 			},
+			"scope": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "The scope of the artifact as reported by CDAP, either user or system.",
+			},
+			"plugin_classes": pluginClassesSchema("The plugin classes the uploaded artifact registers, as reported by CDAP."),
 		},
 	}
 }
 
 func resourceGCSArtifactCreate(d *schema.ResourceData, m interface{}) error {
-	ctx := context.Background()
 	config := m.(*Config)
+	ctx := config.httpClient.stopCtx
+	if err := setDefaultNamespace(d, config); err != nil {
+		return err
+	}
 
-	a, err := loadGCSArtifact(ctx, d, config.storageClient)
+	a, err := loadGCSArtifact(ctx, d, config)
 	if err != nil {
 		return err
 	}
 	return uploadArtifact(config, d, a)
 }
 
-func loadGCSArtifact(ctx context.Context, d *schema.ResourceData, storageClient *storage.Client) (*artifact, error) {
-	jar, err := readObject(ctx, storageClient, d.Get("jar_binary_path").(string))
+func loadGCSArtifact(ctx context.Context, d *schema.ResourceData, config *Config) (*artifact, error) {
+	storageClient := config.storageClient
+	jarPath := d.Get("jar_binary_path").(string)
+	jar, err := readObject(ctx, storageClient, jarPath)
 	if err != nil {
 		return nil, err
 	}
+	if err := checkJarSize(config, int64(len(jar)), jarPath); err != nil {
+		return nil, err
+	}
+	if err := validateArtifactVersion(bytes.NewReader(jar), int64(len(jar)), d.Get("version").(string)); err != nil {
+		return nil, err
+	}
 
-	confb, err := readObject(ctx, storageClient, d.Get("json_config_path").(string))
+	confPath := d.Get("json_config_path").(string)
+	confb, err := readObject(ctx, storageClient, confPath)
 	if err != nil {
 		return nil, err
 	}
-	conf := new(artifactConfig)
-	if err := json.Unmarshal(confb, conf); err != nil {
+	conf, err := parseArtifactConfig(confb, confPath)
+	if err != nil {
 		return nil, err
 	}
 
 	return &artifact{
 		name:    d.Get("name").(string),
 		version: d.Get("version").(string),
-		jar:     jar,
+		jar:     jarFromBytes(jar),
 		config:  conf,
 	}, nil
 }
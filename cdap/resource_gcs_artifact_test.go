@@ -0,0 +1,76 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cdap
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+// cdap_gcs_artifact shares uploadArtifact with cdap_local_artifact, so the
+// checksum of its JAR must come from the downloaded bytes rather than from
+// jar_binary_path, which is a bucket path.
+func TestGCSArtifactUpload(t *testing.T) {
+	jar := []byte("example jar")
+	var recorded map[string]string
+	config := newTestConfig(t, func(w http.ResponseWriter, r *http.Request) {
+		base := "/v3/namespaces/default/artifacts/example"
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == base:
+			w.Write([]byte("[]"))
+		case r.Method == http.MethodPost && r.URL.Path == base:
+		case r.Method == http.MethodPost && r.URL.Path == base+"/versions/1.0.0/metadata/properties":
+			b, _ := ioutil.ReadAll(r.Body)
+			if err := json.Unmarshal(b, &recorded); err != nil {
+				t.Errorf("failed to decode metadata %q: %v", b, err)
+			}
+		case r.Method == http.MethodPut && r.URL.Path == base+"/versions/1.0.0/properties":
+		case r.Method == http.MethodGet && r.URL.Path == base+"/versions/1.0.0":
+			w.Write([]byte(`{"name": "example", "version": "1.0.0", "scope": "USER"}`))
+		default:
+			t.Errorf("unexpected request %v %v", r.Method, r.URL)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+	d := schema.TestResourceDataRaw(t, resourceGCSArtifact().Schema, map[string]interface{}{
+		"name":             "example",
+		"namespace":        "default",
+		"version":          "1.0.0",
+		"jar_binary_path":  "gs://bucket/example-1.0.0.jar",
+		"json_config_path": "gs://bucket/example-1.0.0.json",
+	})
+	a := &artifact{
+		name:    "example",
+		version: "1.0.0",
+		jar:     jarFromBytes(jar),
+		config:  &artifactConfig{},
+	}
+	if err := uploadArtifact(config, d, a); err != nil {
+		t.Fatalf("uploadArtifact returned error: %v", err)
+	}
+	if d.Id() != "example" {
+		t.Errorf("ID = %q, want %q", d.Id(), "example")
+	}
+	sum := sha256.Sum256(jar)
+	if got, want := recorded[jarSHA256MetadataKey], hex.EncodeToString(sum[:]); got != want {
+		t.Errorf("recorded checksum = %q, want %q", got, want)
+	}
+}
@@ -15,15 +15,33 @@
 package cdap
 
 import (
+	"archive/zip"
+	"bufio"
 	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"log"
 	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
 	"strings"
+	"time"
 
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/customdiff"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/structure"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
 )
 
 // resourceLocalArtifact supports deploying an artifact by providing a local filepath.
@@ -31,10 +49,15 @@ import (
 // store the entire JAR's contents as a string.
 func resourceLocalArtifact() *schema.Resource {
 	return &schema.Resource{
-		Create: resourceLocalArtifactCreate,
-		Read:   resourceLocalArtifactRead,
-		Delete: resourceLocalArtifactDelete,
-		Exists: resourceLocalArtifactExists,
+		CreateContext: resourceLocalArtifactCreateContext,
+		Read:          resourceLocalArtifactRead,
+		Update:        resourceLocalArtifactUpdate,
+		Delete:        resourceLocalArtifactDelete,
+		Exists:        resourceLocalArtifactExists,
+		Importer: &schema.ResourceImporter{
+			State: resourceLocalArtifactImport,
+		},
+		CustomizeDiff: customdiff.All(resourceLocalArtifactFileDiff, resourceLocalArtifactCustomizeDiff, resourceLocalArtifactHashDiff, resourceLocalArtifactUploadConfigDiff, resourceLocalArtifactSizeDiff),
 
 		Schema: map[string]*schema.Schema{
 			"name": {
@@ -46,11 +69,9 @@ func resourceLocalArtifact() *schema.Resource {
 			"namespace": {
 				Type:        schema.TypeString,
 				Optional:    true,
+				Computed:    true,
 				ForceNew:    true,
-				Description: "The name of the namespace in which this resource belongs. If not provided, the default namespace is used.",
-				DefaultFunc: func() (interface{}, error) {
-					return defaultNamespace, nil
-				},
+				Description: "The name of the namespace in which this resource belongs. If not provided, the provider's default_namespace is used.",
 			},
 			// Technically, we could omit the version in the API call because CDAP will infer the
 			// version from the jar. However, forcing the user to specify the version makes dealing
@@ -61,19 +82,164 @@ func resourceLocalArtifact() *schema.Resource {
 				ForceNew:    true,
 				Description: "The version of the artifact. Must match the version in the JAR manifest.",
 			},
+			// The paths are not ForceNew since moving a file does not need a re-upload.
+			// Instead, the artifact is replaced when the hash of a file changes.
 			"jar_binary_path": {
 				Type:        schema.TypeString,
 				Required:    true,
-				ForceNew:    true,
 				Description: "The local path to the JAR binary for the artifact.",
 			},
 			"json_config_path": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				ExactlyOneOf: []string{"json_config", "json_config_path"},
+				Description:  "The local path to the JSON config of the artifact.",
+			},
+			"json_config": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				ExactlyOneOf: []string{"json_config", "json_config_path"},
+				ValidateFunc: validation.StringIsJSON,
+				StateFunc: func(v interface{}) string {
+					json, _ := structure.NormalizeJsonString(v)
+					return json
+				},
+				Description: "The JSON config of the artifact, for small configs that are not worth a file, such as ones generated with jsonencode.",
+			},
+			"jar_sha256": {
 				Type:        schema.TypeString,
-				Required:    true,
+				Computed:    true,
+				Description: "The hex encoded SHA-256 checksum of the uploaded JAR. The artifact is replaced when the JAR on disk no longer matches it. The checksum is also recorded in the terraform_jar_sha256 metadata property of the artifact, so creating the resource again, for example after it was removed from state, skips the upload if the artifact version was already uploaded from the same JAR.",
+			},
+			"json_config_sha256": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "The hex encoded SHA-256 checksum of the uploaded JSON config. The properties of the artifact are updated when the config on disk no longer matches it.",
+			},
+			"json_config_upload_sha256": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "The hex encoded SHA-256 checksum of the parts of the JSON config that are sent along with the JAR, its parents and plugins. CDAP can only change them by uploading the JAR again, so the artifact is replaced when they change, while changes to only the properties of the config are applied in place.",
+			},
+			"scope": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				ForceNew:     true,
+				Default:      "user",
+				ValidateFunc: validation.StringInSlice([]string{"user", "system"}, false),
+				Description:  "The scope of the artifact, either user or system. System artifacts are shared across all namespaces and are uploaded to the system namespace regardless of namespace.",
+			},
+			"properties": {
+				Type:        schema.TypeMap,
+				Optional:    true,
+				Description: "The properties of the artifact. If set, these take precedence over the properties in the JSON config. Changing them updates the artifact in place without re-uploading the JAR.",
+				Elem: &schema.Schema{
+					Type: schema.TypeString,
+				},
+			},
+			"properties_source": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				Default:      "inline",
+				ValidateFunc: validation.StringInSlice([]string{"file", "inline", "merge"}, false),
+				Description:  "Where the properties of the artifact come from. With inline, properties replaces the properties of the JSON config if set. With file, only the properties of the JSON config are used and properties must not be set. With merge, properties are added to the ones of the JSON config, taking precedence on conflicts, and only the keys in properties are tracked for drift.",
+			},
+			"properties_merge": {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Default:     false,
+				Description: "Whether updates of properties only change the properties managed by this resource, keeping other properties of the artifact such as the ones set by CDAP or other tools. Only the managed properties are then tracked for drift. By default updates replace all properties, which removes unmanaged ones but guarantees the artifact has exactly the configured properties.",
+			},
+			"properties_incremental": {
+				Type:          schema.TypeBool,
+				Optional:      true,
+				Default:       false,
+				ConflictsWith: []string{"properties_merge"},
+				Description:   "Whether updates of properties only set the properties that were added or changed and delete the ones that were removed, one request per key, instead of replacing all properties. As with properties_merge, other properties of the artifact are kept and only the managed properties are tracked for drift, but the current properties do not need to be read first, which suits large property maps. Creating the artifact still sets all properties at once.",
+			},
+			"verify_upload": {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Default:     true,
+				Description: "Whether to wait after the upload until CDAP lists the artifact version, so resources using the artifact do not fail right after it is created.",
+			},
+			"verify_integrity": {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Default:     false,
+				Description: "Whether to download the JAR again after the upload and compare its SHA-256 checksum with jar_sha256, failing the apply if CDAP stored something else, such as after corruption in transit. CDAP does not report checksums of stored artifacts, so this transfers the JAR a second time and holds it in memory while it is hashed, which can take a while for large JARs.",
+			},
+			"immutable": {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Default:     false,
 				ForceNew:    true,
-				Description: "The local path to the JSON config of the artifact.",
+				Description: "Whether published artifact versions are frozen. If the version already exists when the resource is created, its JAR is downloaded and compared with the local JAR by SHA-256 checksum: if they differ, the apply fails instead of overwriting the artifact, and if they match, the JAR is not uploaded again and only the properties and tags are set. CDAP does not report checksums of stored artifacts, so the check transfers the existing JAR, which can take a while for large JARs.",
+			},
+			"create_namespace": {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Default:     false,
+				Description: "Whether to create the namespace before uploading the artifact if it does not exist yet. The namespace is not deleted with the artifact since other resources may share it.",
+			},
+			"depends_on_artifacts": {
+				Type:        schema.TypeList,
+				Optional:    true,
+				Description: "Artifacts that must exist before this artifact is uploaded, such as the plugin artifacts it uses. They are checked before the upload so missing artifacts are reported right away instead of when a pipeline runs.",
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"name": {
+							Type:        schema.TypeString,
+							Required:    true,
+							Description: "The name of the artifact.",
+						},
+						"version": {
+							Type:        schema.TypeString,
+							Required:    true,
+							Description: "The version of the artifact.",
+						},
+						"scope": {
+							Type:         schema.TypeString,
+							Optional:     true,
+							Default:      "user",
+							ValidateFunc: validation.StringInSlice([]string{"user", "system"}, false),
+							Description:  "The scope of the artifact, either user or system.",
+						},
+					},
+				},
+			},
+			"skip_namespace_check": {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Default:     false,
+				Description: "Whether to skip checking that the namespace exists before uploading the artifact, which saves a call to the instance. Has no effect if create_namespace is set.",
+			},
+			"delete_all_versions": {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Default:     false,
+				Description: "Whether to delete every version of the artifact in its scope when the resource is destroyed, including versions uploaded outside of Terraform. By default only the version managed by this resource is deleted.",
+			},
+			"skip_file_check": {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Default:     false,
+				Description: "Whether to skip checking during plan that jar_binary_path and json_config_path are readable. Set it when the files are generated during the apply, for example by a local-exec provisioner. Paths that are only known after apply are never checked.",
+			},
+			"plugin_classes": pluginClassesSchema("The plugin classes the uploaded artifact registers, as reported by CDAP. Changes to them show whether a rebuilt JAR changed its plugins."),
+			"tags": {
+				Type:        schema.TypeSet,
+				Optional:    true,
+				Description: "User metadata tags to add to the artifact once it is uploaded. Only these tags are tracked, so tags added by other tools are left untouched.",
+				Elem:        &schema.Schema{Type: schema.TypeString},
 			},
 		},
+		// Fat JARs can be hundreds of MB, so allow plenty of time for the upload.
+		Timeouts: &schema.ResourceTimeout{
+			Create: schema.DefaultTimeout(10 * time.Minute),
+			Update: schema.DefaultTimeout(5 * time.Minute),
+			Delete: schema.DefaultTimeout(5 * time.Minute),
+		},
 	}
 }
 
@@ -81,59 +247,515 @@ type artifact struct {
 	name    string
 	version string
 	config  *artifactConfig
-	jar     []byte
+	// jar opens the JAR binary and returns it along with its size. It may be
+	// called more than once, for example when an upload is retried.
+	jar func() (io.ReadCloser, int64, error)
+}
+
+// jarFromBytes returns a JAR source for a JAR that is already in memory.
+func jarFromBytes(b []byte) func() (io.ReadCloser, int64, error) {
+	return func() (io.ReadCloser, int64, error) {
+		return ioutil.NopCloser(bytes.NewReader(b)), int64(len(b)), nil
+	}
+}
+
+// jarFromFile returns a JAR source that streams the JAR from disk, so large
+// JARs are never fully loaded into memory.
+func jarFromFile(path string) func() (io.ReadCloser, int64, error) {
+	return func() (io.ReadCloser, int64, error) {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, 0, err
+		}
+		fi, err := f.Stat()
+		if err != nil {
+			f.Close()
+			return nil, 0, err
+		}
+		return f, fi.Size(), nil
+	}
 }
 
 type artifactConfig struct {
 	Properties map[string]string `json:"properties"`
-	Parents    []string          `json:"parents"`
+	Parents    artifactParents   `json:"parents"`
+	// Plugins lists plugin classes to register for JARs that do not declare
+	// their plugins through annotations.
+	Plugins []*pluginClass `json:"plugins"`
 }
 
 func resourceLocalArtifactCreate(d *schema.ResourceData, m interface{}) error {
 	config := m.(*Config)
-	a, err := loadLocalArtifact(d)
+	if err := setDefaultNamespace(d, config); err != nil {
+		return err
+	}
+	a, err := loadLocalArtifact(d, config)
 	if err != nil {
 		return err
 	}
-	return uploadArtifact(config, d, a)
+
+	namespace := d.Get("namespace").(string)
+	switch {
+	case artifactNamespace(d) == systemNamespace:
+	case d.Get("create_namespace").(bool):
+		if err := ensureNamespace(config, namespace); err != nil {
+			return err
+		}
+	case !d.Get("skip_namespace_check").(bool):
+		// Otherwise the upload fails with a 404 that does not say what is missing.
+		exists, err := namespaceExists(config, namespace)
+		if err != nil {
+			return fmt.Errorf("failed to check for existence of namespace %q: %v", namespace, err)
+		}
+		if !exists {
+			return fmt.Errorf("namespace %q does not exist, create it first or set create_namespace to true", namespace)
+		}
+	}
+	if err := checkArtifactDependencies(d, config); err != nil {
+		return err
+	}
+	var propsErr *propertiesError
+	if err := uploadArtifact(config, d, a); err != nil && !(errors.As(err, &propsErr) && propertiesRetried(d)) {
+		return err
+	}
+	if err := setLocalArtifactHashes(d, config); err != nil {
+		return err
+	}
+	sum, err := uploadConfigSHA256(a.config)
+	if err != nil {
+		return err
+	}
+	d.Set("json_config_upload_sha256", sum)
+	if d.Get("verify_integrity").(bool) {
+		if err := verifyArtifactIntegrity(d, config); err != nil {
+			return err
+		}
+	}
+	if err := addMetadata(config, artifactMetadataAddr(config, d), setToStrings(d.Get("tags").(*schema.Set)), nil); err != nil {
+		return fmt.Errorf("failed to tag artifact %q: %v", a.name, err)
+	}
+	if propsErr != nil {
+		// The next plan records the checksum again, which updates the
+		// properties from the JSON config.
+		d.Set("json_config_sha256", "")
+		return propsErr
+	}
+	return nil
+}
+
+// resourceLocalArtifactCreateContext reports properties that could not be set
+// on the uploaded artifact as a warning. Terraform taints resources whose
+// create fails, which would upload the JAR again, while the next apply only
+// needs to set the properties.
+func resourceLocalArtifactCreateContext(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	err := resourceLocalArtifactCreate(d, m)
+	var propsErr *propertiesError
+	if errors.As(err, &propsErr) {
+		return diag.Diagnostics{{
+			Severity: diag.Warning,
+			Summary:  propsErr.Error(),
+			Detail:   "The properties are set again by the next apply.",
+		}}
+	}
+	return diag.FromErr(err)
+}
+
+// propertiesRetried reports whether the next plan of an artifact whose
+// properties could not be set shows a change that sets them again: either the
+// checksum of json_config_path, or the properties attribute differing from
+// the ones CDAP has.
+func propertiesRetried(d *schema.ResourceData) bool {
+	if d.Get("json_config_path").(string) != "" {
+		return true
+	}
+	_, ok := inlineProperties(d)
+	return ok && d.Get("properties_source").(string) == "inline"
+}
+
+// verifyArtifactIntegrity downloads the uploaded JAR and compares its
+// checksum with the one of the local JAR recorded in jar_sha256.
+func verifyArtifactIntegrity(d *schema.ResourceData, config *Config) error {
+	name, version := d.Get("name").(string), d.Get("version").(string)
+	ctx, cancel := context.WithTimeout(config.httpClient.stopCtx, d.Timeout(schema.TimeoutCreate))
+	defer cancel()
+	got, err := storedArtifactSHA256(ctx, config, d)
+	if err != nil {
+		return fmt.Errorf("failed to download artifact %q version %q to verify it: %v", name, version, err)
+	}
+	if want := d.Get("jar_sha256").(string); got != want {
+		return fmt.Errorf("artifact %q version %q stored by CDAP has SHA-256 %s, but the uploaded JAR has %s, the JAR may have been corrupted or altered in transit (the resource is tainted and uploaded again on the next apply)", name, version, got, want)
+	}
+	return nil
+}
+
+// storedArtifactSHA256 downloads the JAR of the artifact version and returns
+// its SHA-256 checksum.
+func storedArtifactSHA256(ctx context.Context, config *Config, d *schema.ResourceData) (string, error) {
+	addr := urlJoin(config.host, "/v3/namespaces", artifactNamespace(d), "/artifacts", d.Get("name").(string), "/versions", d.Get("version").(string), "/download")
+	addr += "?scope=" + url.QueryEscape(strings.ToUpper(d.Get("scope").(string)))
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, addr, nil)
+	if err != nil {
+		return "", err
+	}
+	b, err := httpCall(config.httpClient, req)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// checkImmutableArtifact reports whether the artifact version already exists
+// with the same JAR, in which case it must not be uploaded again. It returns
+// an error if it exists with a different JAR. Without immutable, which only
+// cdap_local_artifact has, existing versions are overwritten.
+func checkImmutableArtifact(ctx context.Context, config *Config, d *schema.ResourceData, a *artifact) (bool, error) {
+	if immutable, _ := d.Get("immutable").(bool); !immutable {
+		return false, nil
+	}
+	exists, err := artifactVersionExists(config, artifactNamespace(d), a.name, a.version, d.Get("scope").(string))
+	if err != nil {
+		return false, fmt.Errorf("failed to check for existence of artifact %q version %q: %v", a.name, a.version, err)
+	}
+	if !exists {
+		return false, nil
+	}
+
+	got, err := storedArtifactSHA256(ctx, config, d)
+	if err != nil {
+		return false, fmt.Errorf("failed to download existing artifact %q version %q to compare it: %v", a.name, a.version, err)
+	}
+	want, err := jarSHA256(a)
+	if err != nil {
+		return false, err
+	}
+	if got != want {
+		return false, fmt.Errorf("artifact %q version %q already exists with a different JAR (SHA-256 %s, local JAR %s) and immutable is set, publish the change under a new version", a.name, a.version, got, want)
+	}
+	log.Printf("[DEBUG] artifact %q version %q already exists with the same JAR, skipping the upload", a.name, a.version)
+	return true, nil
+}
+
+// jarSHA256MetadataKey is the metadata property holding the checksum of the
+// JAR an artifact version was uploaded from.
+const jarSHA256MetadataKey = "terraform_jar_sha256"
+
+// artifactJarUnchanged reports whether the artifact version already exists and
+// was uploaded from a JAR with the given checksum, so uploading it again can
+// be skipped. CDAP keeps no checksums of artifacts, so the checksum recorded
+// in the metadata of the artifact at upload is compared.
+func artifactJarUnchanged(config *Config, d *schema.ResourceData, a *artifact, sum string) (bool, error) {
+	exists, err := artifactVersionExists(config, artifactNamespace(d), a.name, a.version, d.Get("scope").(string))
+	if err != nil {
+		return false, fmt.Errorf("failed to check for existence of artifact %q version %q: %v", a.name, a.version, err)
+	}
+	if !exists {
+		return false, nil
+	}
+	props := make(map[string]string)
+	if err := getMetadata(config, urlJoin(artifactMetadataAddr(config, d), "/properties"), &props); err != nil {
+		return false, permissionError(err, fmt.Sprintf("read metadata of artifact %q", a.name))
+	}
+	if props[jarSHA256MetadataKey] != sum {
+		return false, nil
+	}
+	log.Printf("[DEBUG] artifact %q version %q was already uploaded from the same JAR, skipping the upload", a.name, a.version)
+	return true, nil
+}
+
+// jarSHA256 returns the SHA-256 checksum of the JAR of the artifact.
+func jarSHA256(a *artifact) (string, error) {
+	r, _, err := a.jar()
+	if err != nil {
+		return "", err
+	}
+	defer r.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, r); err != nil {
+		return "", fmt.Errorf("failed to read JAR of artifact %q: %v", a.name, err)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// artifactTags returns the tags managed by the resource. The artifact
+// resources sharing Read and Delete with cdap_local_artifact have no tags
+// attribute, so they manage none.
+func artifactTags(d *schema.ResourceData) *schema.Set {
+	if tags, ok := d.Get("tags").(*schema.Set); ok {
+		return tags
+	}
+	return schema.NewSet(schema.HashString, nil)
+}
+
+// artifactMetadataAddr returns the metadata endpoint of the artifact version,
+// in the namespace the artifact is managed in.
+func artifactMetadataAddr(config *Config, d *schema.ResourceData) string {
+	return urlJoin(config.host, "/v3/namespaces", artifactNamespace(d), "/artifacts", d.Get("name").(string), "/versions", d.Get("version").(string), "/metadata")
+}
+
+// localArtifactFiles maps the path attributes of a local artifact to the
+// attributes holding their checksums, and whether a change of the file
+// replaces the artifact. Changes of the JSON config only replace it if the
+// parts uploaded with the JAR change, see resourceLocalArtifactUploadConfigDiff.
+var localArtifactFiles = []struct {
+	path, hash string
+	forceNew   bool
+}{
+	{"jar_binary_path", "jar_sha256", true},
+	{"json_config_path", "json_config_sha256", false},
+}
+
+// uploadConfigSHA256 returns the checksum of the parts of the config sent
+// along with the JAR.
+func uploadConfigSHA256(conf *artifactConfig) (string, error) {
+	b, err := json.Marshal(struct {
+		Parents artifactParents `json:"parents"`
+		Plugins []*pluginClass  `json:"plugins"`
+	}{conf.Parents, conf.Plugins})
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+func setLocalArtifactHashes(d *schema.ResourceData, config *Config) error {
+	for _, f := range localArtifactFiles {
+		// There is no file to hash for an inline JSON config.
+		if d.Get(f.path).(string) == "" {
+			continue
+		}
+		sum, err := fileSHA256(localPath(config, d.Get(f.path).(string)))
+		if err != nil {
+			return err
+		}
+		d.Set(f.hash, sum)
+	}
+	return nil
+}
+
+// ensureNamespace creates the namespace if it does not exist yet.
+func ensureNamespace(config *Config, namespace string) error {
+	exists, err := namespaceExists(config, namespace)
+	if err != nil {
+		return fmt.Errorf("failed to check for existence of namespace %q: %v", namespace, err)
+	}
+	if exists {
+		return nil
+	}
+	if err := createNamespace(config, namespace, nil); err != nil {
+		return fmt.Errorf("failed to create namespace %q: %v", namespace, err)
+	}
+	return nil
+}
+
+// systemNamespace is the namespace system scoped artifacts belong to.
+const systemNamespace = "system"
+
+// artifactNamespace returns the namespace the artifact of the resource is
+// managed in, which is the system namespace for system scoped artifacts.
+func artifactNamespace(d *schema.ResourceData) string {
+	if d.Get("scope").(string) == "system" {
+		return systemNamespace
+	}
+	return d.Get("namespace").(string)
 }
 
 func uploadArtifact(config *Config, d *schema.ResourceData, a *artifact) error {
-	addr := urlJoin(config.host, "/v3/namespaces", d.Get("namespace").(string), "/artifacts", a.name)
+	addr := urlJoin(config.host, "/v3/namespaces", artifactNamespace(d), "/artifacts", a.name)
+
+	timeout := d.Timeout(schema.TimeoutCreate)
+	ctx, cancel := context.WithTimeout(config.httpClient.stopCtx, timeout)
+	defer cancel()
 
-	if err := uploadJar(config.httpClient, addr, a); err != nil {
+	skip, err := checkImmutableArtifact(ctx, config, d, a)
+	if err != nil {
+		return err
+	}
+	// The checksum is taken from the JAR that is uploaded rather than from
+	// jar_binary_path, which is a bucket path for cdap_gcs_artifact.
+	sum, err := jarSHA256(a)
+	if err != nil {
 		return err
 	}
+	if !skip {
+		if skip, err = artifactJarUnchanged(config, d, a, sum); err != nil {
+			return err
+		}
+	}
+	if !skip {
+		if err := uploadJar(ctx, config, addr, a); err != nil {
+			if errors.Is(err, context.DeadlineExceeded) {
+				return fmt.Errorf("upload of artifact %q did not finish within the create timeout of %v, consider increasing it: %v", a.name, timeout, err)
+			}
+			return impersonationError(config, artifactNamespace(d), err)
+		}
+		// Not recording the checksum only means the next create uploads the JAR again.
+		if err := addMetadata(config, artifactMetadataAddr(config, d), nil, map[string]interface{}{jarSHA256MetadataKey: sum}); err != nil {
+			log.Printf("[WARN] failed to record the checksum of artifact %q version %q: %v", a.name, a.version, err)
+		}
+	}
 	d.SetId(a.name)
 
-	if err := uploadProps(config.httpClient, addr, a); err != nil {
-		return err
+	if v, ok := d.GetOk("verify_upload"); ok && v.(bool) {
+		err := pollUntil(ctx, time.Second, func() (bool, error) {
+			_, err := getArtifactDetail(config, artifactNamespace(d), a.name, a.version, "")
+			if isNotFound(err) {
+				return false, nil
+			}
+			return err == nil, err
+		})
+		if err != nil {
+			return fmt.Errorf("failed to verify upload of artifact %q version %q: %v", a.name, a.version, err)
+		}
+	}
+
+	if err := uploadProps(ctx, config.httpClient, addr, a); err != nil {
+		// The JAR is uploaded and the resource stays in state. Record the
+		// properties CDAP actually has, so they are set again by an update
+		// instead of re-uploading the JAR.
+		var props map[string]string
+		if ad, err := getArtifactDetail(config, artifactNamespace(d), a.name, a.version, ""); err == nil {
+			props = ad.Properties
+		}
+		d.Set("properties", props)
+
+		if errors.Is(err, context.DeadlineExceeded) {
+			err = fmt.Errorf("did not finish within the create timeout of %v: %v", timeout, err)
+		}
+		return &propertiesError{name: a.name, err: err}
+	}
+
+	// Read the plugin classes CDAP found in the JAR, so they can be referenced
+	// in the same apply.
+	ad, err := getArtifactDetail(config, artifactNamespace(d), a.name, a.version, "")
+	if err != nil {
+		return fmt.Errorf("failed to read plugin classes of artifact %q version %q: %v", a.name, a.version, err)
 	}
+	d.Set("plugin_classes", flattenPluginClasses(ad.Classes.Plugins))
 	return nil
 }
 
-func uploadJar(client *http.Client, addr string, a *artifact) error {
-	req, err := http.NewRequest(http.MethodPost, addr, bytes.NewReader(a.jar))
+// propertiesError is returned by uploadArtifact when the JAR was uploaded but
+// setting the properties of the artifact failed.
+type propertiesError struct {
+	name string
+	err  error
+}
+
+func (e *propertiesError) Error() string {
+	return fmt.Sprintf("artifact %q was uploaded but setting its properties failed: %v", e.name, e.err)
+}
+
+func (e *propertiesError) Unwrap() error {
+	return e.err
+}
+
+// impersonationError explains authorization failures in namespaces with
+// impersonation configured. CDAP has no per request principal, so the
+// principal and keytab of the namespace are always used, and a failure usually
+// means CDAP cannot log in as the principal or the principal lacks access.
+func impersonationError(config *Config, namespace string, err error) error {
+	var httpErr *httpError
+	if !errors.As(err, &httpErr) || (httpErr.code != http.StatusUnauthorized && httpErr.code != http.StatusForbidden && httpErr.code != http.StatusInternalServerError) {
+		return err
+	}
+
+	req, reqErr := http.NewRequest(http.MethodGet, urlJoin(config.host, "/v3/namespaces", namespace), nil)
+	if reqErr != nil {
+		return err
+	}
+	b, getErr := httpCall(config.httpClient, req)
+	if getErr != nil {
+		return err
+	}
+	meta := new(namespaceMeta)
+	if json.Unmarshal(b, meta) != nil || meta.Config == nil || meta.Config.Principal == "" {
+		return err
+	}
+	return fmt.Errorf("namespace %q impersonates principal %q with keytab %q, make sure CDAP can read the keytab and the principal has access: %v", namespace, meta.Config.Principal, meta.Config.KeytabURI, err)
+}
+
+func uploadJar(ctx context.Context, config *Config, addr string, a *artifact) error {
+	open := a.jar
+	if config.gzipUploads {
+		open = gzipJar(a.jar)
+	}
+
+	body, size, err := open()
 	if err != nil {
 		return err
 	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, addr, body)
+	if err != nil {
+		body.Close()
+		return err
+	}
+	req.ContentLength = size
+	req.GetBody = func() (io.ReadCloser, error) {
+		body, _, err := open()
+		return body, err
+	}
+
 	req.Header = map[string][]string{}
+	req.Header.Set("Content-Type", config.uploadContentType)
+	if config.gzipUploads {
+		req.Header.Add("Content-Encoding", "gzip")
+	}
 	req.Header.Add("Artifact-Version", a.version)
 	req.Header.Add("Artifact-Extends", strings.Join(a.config.Parents, "/"))
-	if _, err := httpCall(client, req); err != nil {
+	// CDAP only accepts explicit plugin classes as part of the upload.
+	if len(a.config.Plugins) > 0 {
+		b, err := json.Marshal(a.config.Plugins)
+		if err != nil {
+			return err
+		}
+		req.Header.Add("Artifact-Plugins", string(b))
+	}
+	if _, err := httpCall(config.httpClient, req); err != nil {
 		return err
 	}
 	return nil
 }
 
-func uploadProps(client *http.Client, artifactAddr string, a *artifact) error {
+// gzipJar wraps a JAR source so the JAR is gzip compressed while it is read.
+// The compressed size is not known up front, so it is reported as -1 and the
+// upload is sent chunked.
+func gzipJar(open func() (io.ReadCloser, int64, error)) func() (io.ReadCloser, int64, error) {
+	return func() (io.ReadCloser, int64, error) {
+		r, _, err := open()
+		if err != nil {
+			return nil, 0, err
+		}
+		pr, pw := io.Pipe()
+		go func() {
+			defer r.Close()
+			gw := gzip.NewWriter(pw)
+			_, err := io.Copy(gw, r)
+			if err == nil {
+				err = gw.Close()
+			}
+			pw.CloseWithError(err)
+		}()
+		return pr, -1, nil
+	}
+}
+
+// uploadProps replaces all properties of the artifact, so properties that are
+// no longer set are removed.
+func uploadProps(ctx context.Context, client *apiClient, artifactAddr string, a *artifact) error {
 	addr := urlJoin(artifactAddr, "/versions", a.version, "/properties")
-	b, err := json.Marshal(a.config.Properties)
+	props := a.config.Properties
+	if props == nil {
+		// Send an empty object rather than null so existing properties are cleared.
+		props = map[string]string{}
+	}
+	b, err := json.Marshal(props)
 	if err != nil {
 		return err
 	}
 	body := bytes.NewReader(b)
-	req, err := http.NewRequest(http.MethodPut, addr, body)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, addr, body)
 	if err != nil {
 		return err
 	}
@@ -143,43 +765,846 @@ func uploadProps(client *http.Client, artifactAddr string, a *artifact) error {
 	return nil
 }
 
-func loadLocalArtifact(d *schema.ResourceData) (*artifact, error) {
-	jar, err := ioutil.ReadFile(d.Get("jar_binary_path").(string))
+func loadLocalArtifact(d *schema.ResourceData, config *Config) (*artifact, error) {
+	conf, err := localArtifactConfig(d, config)
 	if err != nil {
 		return nil, err
 	}
+	a, err := loadArtifactJar(config, d.Get("name").(string), d.Get("version").(string), localPath(config, d.Get("jar_binary_path").(string)))
+	if err != nil {
+		return nil, err
+	}
+	a.config = conf
+	a.config.Properties = artifactProperties(d, conf)
+	return a, nil
+}
+
+// artifactProperties returns the properties to set on the artifact, combining
+// the properties of the JSON config and the properties attribute as chosen by
+// properties_source.
+func artifactProperties(d *schema.ResourceData, conf *artifactConfig) map[string]string {
+	inline, ok := inlineProperties(d)
+	switch d.Get("properties_source").(string) {
+	case "file":
+		return conf.Properties
+	case "merge":
+		props := make(map[string]string, len(conf.Properties)+len(inline))
+		for k, v := range conf.Properties {
+			props[k] = v
+		}
+		for k, v := range inline {
+			props[k] = v
+		}
+		return props
+	}
+	if ok {
+		return inline
+	}
+	return conf.Properties
+}
 
-	confb, err := ioutil.ReadFile(d.Get("json_config_path").(string))
+// loadArtifactFiles loads an artifact from a JAR and a JSON config on disk,
+// checking that the version matches the JAR manifest.
+func loadArtifactFiles(config *Config, name, version, jarPath, confPath string) (*artifact, error) {
+	conf, err := readArtifactConfig(confPath)
 	if err != nil {
 		return nil, err
 	}
-	conf := new(artifactConfig)
-	if err := json.Unmarshal(confb, conf); err != nil {
+	a, err := loadArtifactJar(config, name, version, jarPath)
+	if err != nil {
+		return nil, err
+	}
+	a.config = conf
+	return a, nil
+}
+
+// loadArtifactJar loads an artifact without config from a JAR on disk,
+// checking that the version matches the JAR manifest.
+func loadArtifactJar(config *Config, name, version, jarPath string) (*artifact, error) {
+	f, err := os.Open(jarPath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if err := checkJarSize(config, fi.Size(), jarPath); err != nil {
+		return nil, err
+	}
+	manifestVersion, err := jarFileManifestVersion(jarPath, f, fi)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkManifestVersion(manifestVersion, version); err != nil {
 		return nil, err
 	}
 
 	return &artifact{
-		name:    d.Get("name").(string),
-		version: d.Get("version").(string),
-		config:  conf,
-		jar:     jar,
+		name:    name,
+		version: version,
+		jar:     jarFromFile(jarPath),
 	}, nil
 }
 
+// localArtifactConfig returns the inline JSON config of the resource, or
+// reads it from json_config_path.
+func localArtifactConfig(d *schema.ResourceData, config *Config) (*artifactConfig, error) {
+	if c, ok := d.GetOk("json_config"); ok {
+		return parseArtifactConfig([]byte(c.(string)), "json_config")
+	}
+	return readArtifactConfig(localPath(config, d.Get("json_config_path").(string)))
+}
+
+// checkArtifactDependencies returns an error listing the artifacts in
+// depends_on_artifacts that do not exist.
+func checkArtifactDependencies(d *schema.ResourceData, config *Config) error {
+	var missing []string
+	for _, v := range d.Get("depends_on_artifacts").([]interface{}) {
+		dep := v.(map[string]interface{})
+		name, version, scope := dep["name"].(string), dep["version"].(string), dep["scope"].(string)
+		exists, err := artifactVersionExists(config, artifactNamespace(d), name, version, scope)
+		if err != nil {
+			return fmt.Errorf("failed to check for existence of dependency %q version %q: %v", name, version, err)
+		}
+		if !exists {
+			missing = append(missing, fmt.Sprintf("%s:%s:%s", scope, name, version))
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("artifact %q depends on artifacts that do not exist in namespace %q: %s", d.Get("name"), artifactNamespace(d), strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// localPath resolves a local file path of a resource against the provider's
+// path_base and makes it absolute, so errors show which file was looked for.
+func localPath(config *Config, path string) string {
+	if config != nil && config.pathBase != "" && !filepath.IsAbs(path) {
+		path = filepath.Join(config.pathBase, path)
+	}
+	if abs, err := filepath.Abs(path); err == nil {
+		return abs
+	}
+	return path
+}
+
+// checkJarSize returns an error if the JAR is larger than the provider's max_jar_bytes.
+func checkJarSize(config *Config, size int64, path string) error {
+	if config.maxJarBytes > 0 && size > config.maxJarBytes {
+		return fmt.Errorf("JAR %q is %d bytes, which is more than max_jar_bytes of %d, check that it points to the right file or increase max_jar_bytes", path, size, config.maxJarBytes)
+	}
+	return nil
+}
+
+// inlineProperties returns the properties set through the properties
+// attribute, if any. An explicitly empty map clears all properties instead of
+// falling back to the ones in the JSON config.
+func inlineProperties(d *schema.ResourceData) (map[string]string, bool) {
+	raw, ok := d.GetOk("properties")
+	if !ok {
+		if c := d.GetRawConfig(); !c.IsNull() && c.IsKnown() && !c.GetAttr("properties").IsNull() {
+			return map[string]string{}, true
+		}
+		return nil, false
+	}
+	props := make(map[string]string)
+	for k, v := range raw.(map[string]interface{}) {
+		props[k] = v.(string)
+	}
+	return props, true
+}
+
+// propertiesInState reports whether the properties attribute is tracked in
+// state. An explicitly empty map is tracked too, so properties added on the
+// instance show up as drift, while an unset attribute is null in state.
+func propertiesInState(d *schema.ResourceData) bool {
+	if _, ok := d.GetOk("properties"); ok {
+		return true
+	}
+	s := d.GetRawState()
+	return !s.IsNull() && s.IsKnown() && s.Type().HasAttribute("properties") && !s.GetAttr("properties").IsNull()
+}
+
+// artifactDetail is the subset of the CDAP artifact detail response the provider uses.
+type artifactDetail struct {
+	Name       string            `json:"name"`
+	Version    string            `json:"version"`
+	Scope      string            `json:"scope"`
+	Properties map[string]string `json:"properties"`
+	Classes    struct {
+		Plugins []*pluginClass `json:"plugins"`
+	} `json:"classes"`
+}
+
+// pluginClass describes a plugin contained in an artifact.
+type pluginClass struct {
+	Name        string `json:"name"`
+	Type        string `json:"type"`
+	ClassName   string `json:"className"`
+	Description string `json:"description"`
+}
+
+func readArtifactConfig(path string) (*artifactConfig, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return parseArtifactConfig(b, path)
+}
+
+// parseArtifactConfig strictly decodes and validates an artifact JSON config.
+// The path is only used in error messages.
+func parseArtifactConfig(b []byte, path string) (*artifactConfig, error) {
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.DisallowUnknownFields()
+
+	conf := new(artifactConfig)
+	if err := dec.Decode(conf); err != nil {
+		return nil, fmt.Errorf("invalid artifact config %q: %v", path, err)
+	}
+	for i, p := range conf.Parents {
+		if _, err := parseArtifactRange(p); err != nil {
+			return nil, fmt.Errorf("invalid artifact config %q: parents[%d] %q is not a valid artifact range: %v", path, i, p, err)
+		}
+	}
+	for i, p := range conf.Plugins {
+		if p.Name == "" || p.Type == "" || p.ClassName == "" {
+			return nil, fmt.Errorf("invalid artifact config %q: plugins[%d] must set name, type and className", path, i)
+		}
+	}
+	return conf, nil
+}
+
+// resourceLocalArtifactCustomizeDiff validates the JSON config at plan time so
+// mistakes surface before anything is uploaded.
+func resourceLocalArtifactCustomizeDiff(ctx context.Context, d *schema.ResourceDiff, m interface{}) error {
+	// cdap_remote_artifact shares this function but has none of the
+	// properties attributes.
+	source, _ := d.Get("properties_source").(string)
+	incremental, _ := d.Get("properties_incremental").(bool)
+	if _, ok := d.GetOk("properties"); ok && source == "file" {
+		return fmt.Errorf("properties cannot be set when properties_source is file")
+	}
+	if incremental && source == "file" {
+		return fmt.Errorf("properties_incremental cannot be set when properties_source is file, since the properties are not tracked in state")
+	}
+	if c, ok := d.GetOk("json_config"); ok {
+		_, err := parseArtifactConfig([]byte(c.(string)), "json_config")
+		return err
+	}
+	if !d.NewValueKnown("json_config_path") || d.Get("json_config_path").(string) == "" {
+		return nil
+	}
+	config, _ := m.(*Config)
+	path := localPath(config, d.Get("json_config_path").(string))
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		// Missing files are reported by resourceLocalArtifactFileDiff, unless
+		// skip_file_check is set because they are generated during the apply.
+		log.Printf("[DEBUG] skipping plan time validation of %q: %v", path, err)
+		return nil
+	}
+	_, err = parseArtifactConfig(b, path)
+	return err
+}
+
+// resourceLocalArtifactFileDiff reports missing or unreadable files at plan
+// time instead of when they are uploaded. Only files about to be uploaded are
+// checked, so existing artifacts can still be planned without their files.
+func resourceLocalArtifactFileDiff(ctx context.Context, d *schema.ResourceDiff, m interface{}) error {
+	if d.Get("skip_file_check").(bool) {
+		return nil
+	}
+	config, _ := m.(*Config)
+	for _, f := range localArtifactFiles {
+		if !d.NewValueKnown(f.path) || d.Get(f.path).(string) == "" {
+			continue
+		}
+		if d.Id() != "" && !d.HasChange(f.path) {
+			continue
+		}
+		path := localPath(config, d.Get(f.path).(string))
+		file, err := os.Open(path)
+		if err != nil {
+			return fmt.Errorf("%s: %v (set skip_file_check if the file is created during the apply)", f.path, err)
+		}
+		file.Close()
+	}
+	return nil
+}
+
+// resourceLocalArtifactSizeDiff reports JARs larger than max_jar_bytes at plan time.
+func resourceLocalArtifactSizeDiff(ctx context.Context, d *schema.ResourceDiff, m interface{}) error {
+	config, ok := m.(*Config)
+	if !ok || !d.NewValueKnown("jar_binary_path") {
+		return nil
+	}
+	path := localPath(config, d.Get("jar_binary_path").(string))
+	fi, err := os.Stat(path)
+	if err != nil {
+		// Missing files are reported by resourceLocalArtifactFileDiff.
+		return nil
+	}
+	return checkJarSize(config, fi.Size(), path)
+}
+
+// resourceLocalArtifactHashDiff replaces the artifact when the JAR or JSON
+// config on disk no longer matches what was uploaded, even if the paths did not
+// change. Moving an unchanged file only updates the path in state.
+func resourceLocalArtifactHashDiff(ctx context.Context, d *schema.ResourceDiff, m interface{}) error {
+	config, _ := m.(*Config)
+	if d.Id() == "" {
+		return planLocalArtifactHashes(d, config)
+	}
+	for _, f := range localArtifactFiles {
+		if !d.NewValueKnown(f.path) {
+			if err := d.ForceNew(f.path); err != nil {
+				return err
+			}
+			continue
+		}
+		if d.Get(f.path).(string) == "" {
+			// Inline JSON configs are replaced through json_config.
+			continue
+		}
+
+		sum, err := fileSHA256(localPath(config, d.Get(f.path).(string)))
+		if err != nil {
+			// The file may be generated later in the apply.
+			log.Printf("[DEBUG] skipping plan time checksum of %q: %v", d.Get(f.path), err)
+			if d.HasChange(f.path) {
+				if err := d.ForceNew(f.path); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+
+		old := d.Get(f.hash).(string)
+		switch {
+		case old == sum:
+		case old == "" && !d.HasChange(f.path):
+			// Resources created before checksums were recorded only need to record it.
+			if err := d.SetNew(f.hash, sum); err != nil {
+				return err
+			}
+		case old == "":
+			// Imported resources have no checksum to compare against.
+			if err := d.ForceNew(f.path); err != nil {
+				return err
+			}
+		default:
+			if err := d.SetNew(f.hash, sum); err != nil {
+				return err
+			}
+			if !f.forceNew {
+				continue
+			}
+			if err := d.ForceNew(f.hash); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// resourceLocalArtifactUploadConfigDiff replaces the artifact when the parents
+// or plugins of its JSON config change, since they can only be set by
+// uploading the JAR. Other changes of the config are updated in place.
+func resourceLocalArtifactUploadConfigDiff(ctx context.Context, d *schema.ResourceDiff, m interface{}) error {
+	config, _ := m.(*Config)
+	if !d.NewValueKnown("json_config") || !d.NewValueKnown("json_config_path") {
+		// Unknown paths already replace the artifact in resourceLocalArtifactHashDiff.
+		if d.Id() != "" && d.HasChange("json_config") {
+			return d.ForceNew("json_config")
+		}
+		return nil
+	}
+
+	var conf *artifactConfig
+	var err error
+	if c, ok := d.GetOk("json_config"); ok {
+		conf, err = parseArtifactConfig([]byte(c.(string)), "json_config")
+	} else {
+		conf, err = readArtifactConfig(localPath(config, d.Get("json_config_path").(string)))
+	}
+	if err != nil {
+		// Missing files replace the artifact in resourceLocalArtifactHashDiff
+		// if their path changed, and are reported by resourceLocalArtifactFileDiff.
+		log.Printf("[DEBUG] skipping plan time checksum of the uploaded JSON config: %v", err)
+		if d.Id() != "" && d.HasChange("json_config") {
+			return d.ForceNew("json_config")
+		}
+		return nil
+	}
+	sum, err := uploadConfigSHA256(conf)
+	if err != nil {
+		return err
+	}
+
+	old := d.Get("json_config_upload_sha256").(string)
+	changed := d.HasChanges("json_config", "json_config_path", "json_config_sha256")
+	switch {
+	case old == sum:
+		return nil
+	case d.Id() == "":
+		return d.SetNew("json_config_upload_sha256", sum)
+	case old == "" && !changed:
+		// Resources created before the checksum was recorded only need to record it.
+		return d.SetNew("json_config_upload_sha256", sum)
+	}
+	// Without a recorded checksum, a changed config may have changed parents.
+	if err := d.SetNew("json_config_upload_sha256", sum); err != nil {
+		return err
+	}
+	return d.ForceNew("json_config_upload_sha256")
+}
+
+// planLocalArtifactHashes shows the checksums of the files about to be
+// uploaded in the plan of a new artifact, when the files already exist.
+func planLocalArtifactHashes(d *schema.ResourceDiff, config *Config) error {
+	for _, f := range localArtifactFiles {
+		if !d.NewValueKnown(f.path) || d.Get(f.path).(string) == "" {
+			continue
+		}
+		sum, err := fileSHA256(localPath(config, d.Get(f.path).(string)))
+		if err != nil {
+			continue
+		}
+		if err := d.SetNew(f.hash, sum); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateArtifactVersion checks that the version declared in the JAR manifest
+// matches the version of the artifact. JARs whose manifest declares no version
+// are not checked.
+func validateArtifactVersion(jar io.ReaderAt, size int64, version string) error {
+	manifestVersion, err := jarManifestVersion(jar, size)
+	if err != nil {
+		return err
+	}
+	return checkManifestVersion(manifestVersion, version)
+}
+
+// checkManifestVersion checks the version in a JAR manifest against the
+// artifact version, if the manifest has one.
+func checkManifestVersion(manifestVersion, version string) error {
+	if manifestVersion == "" {
+		log.Printf("[WARN] JAR manifest has no version, skipping check against artifact version %q", version)
+		return nil
+	}
+	if manifestVersion != version {
+		return fmt.Errorf("artifact version %q does not match version %q in the JAR manifest", version, manifestVersion)
+	}
+	return nil
+}
+
+// jarManifestVersion returns the Bundle-Version or Specification-Version
+// attribute from the main section of the JAR's META-INF/MANIFEST.MF.
+func jarManifestVersion(jar io.ReaderAt, size int64) (string, error) {
+	zr, err := zip.NewReader(jar, size)
+	if err != nil {
+		return "", fmt.Errorf("failed to open JAR: %v", err)
+	}
+
+	var manifest *zip.File
+	for _, f := range zr.File {
+		if strings.EqualFold(f.Name, "META-INF/MANIFEST.MF") {
+			manifest = f
+			break
+		}
+	}
+	if manifest == nil {
+		return "", nil
+	}
+
+	r, err := manifest.Open()
+	if err != nil {
+		return "", fmt.Errorf("failed to open JAR manifest: %v", err)
+	}
+	defer r.Close()
+
+	attrs := make(map[string]string)
+	var last string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if line == "" {
+			// A blank line ends the main section.
+			break
+		}
+		// Long values are continued on lines starting with a single space.
+		if strings.HasPrefix(line, " ") && last != "" {
+			attrs[last] += line[1:]
+			continue
+		}
+		kv := strings.SplitN(line, ":", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		last = strings.TrimSpace(kv[0])
+		attrs[last] = strings.TrimSpace(kv[1])
+	}
+	if err := scanner.Err(); err != nil {
+		return "", fmt.Errorf("failed to read JAR manifest: %v", err)
+	}
+
+	if v := attrs["Bundle-Version"]; v != "" {
+		return v, nil
+	}
+	return attrs["Specification-Version"], nil
+}
+
 func resourceLocalArtifactRead(d *schema.ResourceData, m interface{}) error {
+	config := m.(*Config)
+	namespace := d.Get("namespace").(string)
+
+	ad, err := getArtifactDetail(config, namespace, d.Get("name").(string), d.Get("version").(string), d.Get("scope").(string))
+	if isNotFound(err) {
+		log.Printf("artifact %q not found, removing from state", d.Id())
+		d.SetId("")
+		return nil
+	}
+	if err != nil {
+		return permissionError(err, fmt.Sprintf("read artifact %q", d.Id()))
+	}
+
+	d.Set("name", ad.Name)
+	d.Set("version", ad.Version)
+	d.Set("namespace", namespace)
+	d.Set("scope", strings.ToLower(ad.Scope))
+	// Only track properties in state when they are managed through the
+	// attribute, otherwise properties from the JSON config would show as drift.
+	if propertiesInState(d) {
+		if onlyManagedProperties(d) {
+			d.Set("properties", managedProperties(ad.Properties, d.Get("properties").(map[string]interface{})))
+		} else {
+			d.Set("properties", ad.Properties)
+		}
+	}
+	d.Set("plugin_classes", flattenPluginClasses(ad.Classes.Plugins))
+
+	// Only report the managed tags, so removing one outside of Terraform shows as drift.
+	managed := artifactTags(d)
+	if managed.Len() == 0 {
+		return nil
+	}
+	var tags []string
+	if err := getMetadata(config, urlJoin(artifactMetadataAddr(config, d), "/tags"), &tags); err != nil {
+		return permissionError(err, fmt.Sprintf("read tags of artifact %q", d.Id()))
+	}
+	var gotTags []string
+	for _, t := range tags {
+		if managed.Contains(t) {
+			gotTags = append(gotTags, t)
+		}
+	}
+	d.Set("tags", gotTags)
+	return nil
+}
+
+func resourceLocalArtifactUpdate(d *schema.ResourceData, m interface{}) error {
+	config := m.(*Config)
+	if d.HasChange("tags") {
+		oldTags, newTags := d.GetChange("tags")
+		removed := setToStrings(oldTags.(*schema.Set).Difference(newTags.(*schema.Set)))
+		added := setToStrings(newTags.(*schema.Set).Difference(oldTags.(*schema.Set)))
+		addr := artifactMetadataAddr(config, d)
+		if err := removeMetadata(config, addr, removed, nil); err != nil {
+			return err
+		}
+		if err := addMetadata(config, addr, added, nil); err != nil {
+			return err
+		}
+	}
+	if !d.HasChanges("properties", "properties_source", "json_config", "json_config_path", "json_config_sha256") {
+		return nil
+	}
+
+	props, ok := inlineProperties(d)
+	if !ok || d.Get("properties_source").(string) != "inline" {
+		// The JSON config is only needed if its properties are used.
+		conf, err := localArtifactConfig(d, config)
+		if err != nil {
+			return err
+		}
+		props = artifactProperties(d, conf)
+	}
+
+	// CDAP has no conditional updates of properties, so compare them with the
+	// ones last read to avoid overwriting changes made since.
+	if err := checkPropertiesUnchanged(d, config); err != nil {
+		d.Partial(true)
+		return err
+	}
+	if incrementalProperties(d) {
+		if err := updatePropertiesIncrementally(d, config, props); err != nil {
+			// Keep the previous properties in state so the update is retried.
+			d.Partial(true)
+			return err
+		}
+		return setLocalArtifactHashes(d, config)
+	}
+	if mergeProperties(d) {
+		merged, err := mergedProperties(d, config, props)
+		if err != nil {
+			d.Partial(true)
+			return err
+		}
+		props = merged
+	}
+
+	a := &artifact{
+		name:    d.Get("name").(string),
+		version: d.Get("version").(string),
+		config:  &artifactConfig{Properties: props},
+	}
+	addr := urlJoin(config.host, "/v3/namespaces", artifactNamespace(d), "/artifacts", a.name)
+
+	ctx, cancel := context.WithTimeout(config.httpClient.stopCtx, d.Timeout(schema.TimeoutUpdate))
+	defer cancel()
+	if err := uploadProps(ctx, config.httpClient, addr, a); err != nil {
+		// Keep the previous properties in state so the update is retried.
+		d.Partial(true)
+		return err
+	}
+	// Record the checksum of the JSON config the properties came from.
+	return setLocalArtifactHashes(d, config)
+}
+
+// checkPropertiesUnchanged returns an error if the properties of the artifact
+// on the instance differ from the ones in state. Properties are only tracked
+// in state when set through the properties attribute, otherwise there is
+// nothing to compare against.
+func checkPropertiesUnchanged(d *schema.ResourceData, config *Config) error {
+	o, _ := d.GetChange("properties")
+	old := o.(map[string]interface{})
+	if len(old) == 0 {
+		return nil
+	}
+
+	name, version := d.Get("name").(string), d.Get("version").(string)
+	ad, err := getArtifactDetail(config, artifactNamespace(d), name, version, d.Get("scope").(string))
+	if err != nil {
+		return fmt.Errorf("failed to read the current properties of artifact %q version %q: %v", name, version, err)
+	}
+	props := ad.Properties
+	if onlyManagedProperties(d) {
+		props = managedProperties(props, old)
+	}
+	current := make(map[string]interface{}, len(props))
+	for k, v := range props {
+		current[k] = v
+	}
+	if !reflect.DeepEqual(old, current) {
+		return fmt.Errorf("properties of artifact %q version %q were changed on the instance since they were last read, run terraform plan again to review the changes before applying", name, version)
+	}
 	return nil
 }
 
+// mergeProperties reports whether updates only change the managed properties.
+// Only cdap_local_artifact has the attribute.
+func mergeProperties(d *schema.ResourceData) bool {
+	merge, _ := d.Get("properties_merge").(bool)
+	return merge
+}
+
+// incrementalProperties reports whether updates only send the changed
+// properties. Only cdap_local_artifact has the attribute.
+func incrementalProperties(d *schema.ResourceData) bool {
+	incremental, _ := d.Get("properties_incremental").(bool)
+	return incremental
+}
+
+// onlyManagedProperties reports whether only the properties with keys in the
+// properties attribute are tracked, since the artifact has others too.
+func onlyManagedProperties(d *schema.ResourceData) bool {
+	source, _ := d.Get("properties_source").(string)
+	return mergeProperties(d) || incrementalProperties(d) || source == "merge"
+}
+
+// managedProperties returns the properties with the keys in managed.
+func managedProperties(props map[string]string, managed map[string]interface{}) map[string]string {
+	filtered := make(map[string]string)
+	for k := range managed {
+		if v, ok := props[k]; ok {
+			filtered[k] = v
+		}
+	}
+	return filtered
+}
+
+// mergedProperties returns the current properties of the artifact, without
+// the previously managed ones that were removed and with props applied on top.
+func mergedProperties(d *schema.ResourceData, config *Config, props map[string]string) (map[string]string, error) {
+	name, version := d.Get("name").(string), d.Get("version").(string)
+	ad, err := getArtifactDetail(config, artifactNamespace(d), name, version, d.Get("scope").(string))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the current properties of artifact %q version %q: %v", name, version, err)
+	}
+
+	merged := make(map[string]string, len(ad.Properties))
+	for k, v := range ad.Properties {
+		merged[k] = v
+	}
+	old, _ := d.GetChange("properties")
+	for k := range old.(map[string]interface{}) {
+		if _, ok := props[k]; !ok {
+			delete(merged, k)
+		}
+	}
+	for k, v := range props {
+		merged[k] = v
+	}
+	return merged, nil
+}
+
+// updatePropertiesIncrementally sets the properties of props that differ from
+// the ones in state and deletes the ones in state that are no longer in props.
+// Already deleted properties are treated as deleted, so a partially applied
+// update can be retried.
+func updatePropertiesIncrementally(d *schema.ResourceData, config *Config, props map[string]string) error {
+	name, version := d.Get("name").(string), d.Get("version").(string)
+	addr := urlJoin(config.host, "/v3/namespaces", artifactNamespace(d), "/artifacts", name, "/versions", version, "/properties")
+	o, _ := d.GetChange("properties")
+	old := o.(map[string]interface{})
+
+	ctx, cancel := context.WithTimeout(config.httpClient.stopCtx, d.Timeout(schema.TimeoutUpdate))
+	defer cancel()
+
+	removed, changed := propertiesDelta(old, props)
+	for _, k := range removed {
+		req, err := http.NewRequestWithContext(ctx, http.MethodDelete, urlJoin(addr, url.PathEscape(k)), nil)
+		if err != nil {
+			return err
+		}
+		if _, err := httpCall(config.httpClient, req); err != nil && !isNotFound(err) {
+			return fmt.Errorf("failed to delete property %q of artifact %q version %q: %v", k, name, version, err)
+		}
+	}
+	for _, k := range changed {
+		req, err := http.NewRequestWithContext(ctx, http.MethodPut, urlJoin(addr, url.PathEscape(k)), strings.NewReader(props[k]))
+		if err != nil {
+			return err
+		}
+		if _, err := httpCall(config.httpClient, req); err != nil {
+			return fmt.Errorf("failed to set property %q of artifact %q version %q: %v", k, name, version, err)
+		}
+	}
+	return nil
+}
+
+// propertiesDelta returns the keys of old that are not in props, and the keys
+// of props that are not in old or have a different value, both sorted so the
+// requests are made in a predictable order.
+func propertiesDelta(old map[string]interface{}, props map[string]string) (removed, changed []string) {
+	for k := range old {
+		if _, ok := props[k]; !ok {
+			removed = append(removed, k)
+		}
+	}
+	for k, v := range props {
+		if ov, ok := old[k]; !ok || ov.(string) != v {
+			changed = append(changed, k)
+		}
+	}
+	sort.Strings(removed)
+	sort.Strings(changed)
+	return removed, changed
+}
+
+// getArtifactDetail fetches the detail of an artifact version. If scope is
+// empty, CDAP looks up the artifact in the user scope first.
+func getArtifactDetail(config *Config, namespace, name, version, scope string) (*artifactDetail, error) {
+	addr := urlJoin(config.host, "/v3/namespaces", namespace, "/artifacts", name, "/versions", version)
+	if scope != "" {
+		addr += "?scope=" + url.QueryEscape(strings.ToUpper(scope))
+	}
+
+	req, err := http.NewRequest(http.MethodGet, addr, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	b, err := httpCall(config.httpClient, req)
+	if err != nil {
+		return nil, err
+	}
+
+	ad := new(artifactDetail)
+	if err := json.Unmarshal(b, ad); err != nil {
+		return nil, err
+	}
+	return ad, nil
+}
+
 func resourceLocalArtifactDelete(d *schema.ResourceData, m interface{}) error {
 	config := m.(*Config)
 	name := d.Get("name").(string)
-	addr := urlJoin(config.host, "/v3/namespaces", d.Get("namespace").(string), "/artifacts", name, "/versions", d.Get("version").(string))
+	addr := urlJoin(config.host, "/v3/namespaces", artifactNamespace(d), "/artifacts", name, "/versions", d.Get("version").(string))
+
+	ctx, cancel := context.WithTimeout(config.httpClient.stopCtx, d.Timeout(schema.TimeoutDelete))
+	defer cancel()
+
+	// Remove the tags first, in case CDAP keeps the metadata of deleted artifacts.
+	if err := removeMetadata(config, artifactMetadataAddr(config, d), setToStrings(artifactTags(d)), nil); err != nil && !isNotFound(err) {
+		return permissionError(err, fmt.Sprintf("remove tags of artifact %q", name))
+	}
 
-	req, err := http.NewRequest(http.MethodDelete, addr, nil)
+	// Only cdap_local_artifact has the attribute.
+	if all, _ := d.Get("delete_all_versions").(bool); all {
+		return deleteAllArtifactVersions(ctx, config, d)
+	}
+
+	err := deleteArtifactVersion(ctx, config.httpClient, addr)
+	if isNotFound(err) {
+		// The artifact was already deleted, which is the desired end state.
+		log.Printf("artifact %q version %q already deleted", name, d.Get("version"))
+		return nil
+	}
+	return permissionError(err, fmt.Sprintf("delete artifact %q", name))
+}
+
+// deleteAllArtifactVersions deletes every version of the artifact in the
+// scope of the resource. It keeps going after a failed delete, so a retry
+// only has the remaining versions left to delete.
+func deleteAllArtifactVersions(ctx context.Context, config *Config, d *schema.ResourceData) error {
+	namespace, name, scope := artifactNamespace(d), d.Get("name").(string), d.Get("scope").(string)
+	summaries, err := listArtifactVersions(config, namespace, name, scope)
+	if isNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return permissionError(err, fmt.Sprintf("list versions of artifact %q", name))
+	}
+
+	var failed []string
+	for _, s := range summaries {
+		addr := urlJoin(config.host, "/v3/namespaces", namespace, "/artifacts", name, "/versions", s.Version)
+		if err := deleteArtifactVersion(ctx, config.httpClient, addr); err != nil && !isNotFound(err) {
+			failed = append(failed, fmt.Sprintf("version %q: %v", s.Version, err))
+		}
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("failed to delete versions of artifact %q: %v", name, strings.Join(failed, "; "))
+	}
+	return nil
+}
+
+// deleteArtifactVersion deletes the artifact version at addr. It only reads
+// the client's settings, so it is safe to call concurrently to delete
+// several versions at once.
+func deleteArtifactVersion(ctx context.Context, client *apiClient, addr string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodDelete, addr, nil)
 	if err != nil {
 		return err
 	}
-	_, err = httpCall(config.httpClient, req)
+	_, err = httpCall(client, req)
 	return err
 }
 
@@ -194,35 +1619,54 @@ func resourceLocalArtifactExists(d *schema.ResourceData, m interface{}) (bool, e
 		return false, nil
 	}
 
-	return artifactExists(config, name, namespace)
+	return artifactVersionExists(config, namespace, name, d.Get("version").(string), d.Get("scope").(string))
 }
 
-func artifactExists(config *Config, name, namespace string) (bool, error) {
-	addr := urlJoin(config.host, "/v3/namespaces", namespace, "/artifacts")
+// resourceLocalArtifactImport imports an artifact using an ID of the form
+// namespace/name/version. The JAR and JSON config paths cannot be recovered
+// from CDAP, so they are left empty and the next apply will re-upload the artifact.
+func resourceLocalArtifactImport(d *schema.ResourceData, m interface{}) ([]*schema.ResourceData, error) {
+	config := m.(*Config)
 
-	req, err := http.NewRequest(http.MethodGet, addr, nil)
-	if err != nil {
-		return false, err
+	parts := strings.Split(d.Id(), "/")
+	if len(parts) != 3 || parts[0] == "" || parts[1] == "" || parts[2] == "" {
+		return nil, fmt.Errorf("unexpected import ID %q: want namespace/name/version", d.Id())
 	}
+	namespace, name, version := parts[0], parts[1], parts[2]
 
-	b, err := httpCall(config.httpClient, req)
+	scope := "user"
+	if namespace == systemNamespace {
+		scope = "system"
+	}
+	exists, err := artifactVersionExists(config, namespace, name, version, scope)
 	if err != nil {
-		return false, err
+		return nil, fmt.Errorf("failed to check for existence of artifact %q: %v", name, err)
 	}
-
-	type artifact struct {
-		Name string `json:"name"`
+	if !exists {
+		return nil, fmt.Errorf("artifact %q version %q does not exist in namespace %q", name, version, namespace)
 	}
 
-	var artifacts []artifact
-	if err := json.Unmarshal(b, &artifacts); err != nil {
-		return false, err
-	}
+	d.Set("namespace", namespace)
+	d.Set("name", name)
+	d.Set("version", version)
+	d.Set("scope", scope)
+	d.SetId(name)
+	return []*schema.ResourceData{d}, nil
+}
 
-	log.Printf("got artifacts: %v", artifacts)
+// artifactVersionExists reports whether the given version of the artifact
+// exists. If scope is set, only artifacts in that scope are considered.
+func artifactVersionExists(config *Config, namespace, name, version, scope string) (bool, error) {
+	summaries, err := listArtifactVersions(config, namespace, name, scope)
+	if isNotFound(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, permissionError(err, fmt.Sprintf("list versions of artifact %q", name))
+	}
 
-	for _, a := range artifacts {
-		if a.Name == name {
+	for _, s := range summaries {
+		if s.Version == version {
 			return true, nil
 		}
 	}
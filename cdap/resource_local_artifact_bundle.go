@@ -0,0 +1,345 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cdap
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+// resourceLocalArtifactBundle uploads several artifacts from one directory,
+// for plugin collections that would otherwise need one cdap_local_artifact per
+// JAR. The uploaded artifact versions are tracked in state one by one, so a
+// failed upload only needs the remaining artifacts to be uploaded again.
+// https://docs.cdap.io/cdap/current/en/reference-manual/http-restful-api/artifact.html
+func resourceLocalArtifactBundle() *schema.Resource {
+	return &schema.Resource{
+		Create:        resourceLocalArtifactBundleCreate,
+		Read:          resourceLocalArtifactBundleRead,
+		Update:        resourceLocalArtifactBundleUpdate,
+		Delete:        resourceLocalArtifactBundleDelete,
+		CustomizeDiff: resourceLocalArtifactBundleCustomizeDiff,
+
+		Schema: map[string]*schema.Schema{
+			"namespace": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Computed:    true,
+				ForceNew:    true,
+				Description: "The name of the namespace in which this resource belongs. If not provided, the provider's default_namespace is used.",
+			},
+			"directory": {
+				Type:        schema.TypeString,
+				Required:    true,
+				Description: "The local directory containing the JARs and JSON configs of the artifacts.",
+			},
+			"artifact": {
+				Type:        schema.TypeSet,
+				Required:    true,
+				MinItems:    1,
+				Description: "An artifact of the bundle. Each name and version may only be listed once.",
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"name": {
+							Type:        schema.TypeString,
+							Required:    true,
+							Description: "The name of the artifact.",
+						},
+						"version": {
+							Type:        schema.TypeString,
+							Required:    true,
+							Description: "The version of the artifact. Must match the version in the JAR manifest.",
+						},
+						"jar": {
+							Type:        schema.TypeString,
+							Required:    true,
+							Description: "The path of the JAR binary of the artifact, relative to directory.",
+						},
+						"json_config": {
+							Type:        schema.TypeString,
+							Required:    true,
+							Description: "The path of the JSON config of the artifact, relative to directory.",
+						},
+					},
+				},
+			},
+			"uploaded": {
+				Type:        schema.TypeMap,
+				Computed:    true,
+				Description: "The uploaded artifacts, mapping name/version to the SHA-256 checksums of the JAR and JSON config they were uploaded from.",
+				Elem: &schema.Schema{
+					Type: schema.TypeString,
+				},
+			},
+		},
+		Timeouts: &schema.ResourceTimeout{
+			Create: schema.DefaultTimeout(30 * time.Minute),
+			Update: schema.DefaultTimeout(30 * time.Minute),
+			Delete: schema.DefaultTimeout(10 * time.Minute),
+		},
+	}
+}
+
+// bundleArtifact is an artifact of a bundle along with the files it is
+// uploaded from.
+type bundleArtifact struct {
+	name     string
+	version  string
+	jarPath  string
+	confPath string
+}
+
+func (a *bundleArtifact) key() string {
+	return a.name + "/" + a.version
+}
+
+// checksum returns the checksums of the JAR and JSON config as recorded in
+// the uploaded attribute.
+func (a *bundleArtifact) checksum() (string, error) {
+	jarSum, err := fileSHA256(a.jarPath)
+	if err != nil {
+		return "", err
+	}
+	confSum, err := fileSHA256(a.confPath)
+	if err != nil {
+		return "", err
+	}
+	return jarSum + ":" + confSum, nil
+}
+
+// bundleReader is implemented by both schema.ResourceData and schema.ResourceDiff.
+type bundleReader interface {
+	Get(string) interface{}
+}
+
+// bundleArtifacts returns the artifacts of the bundle sorted by name and
+// version, so uploads happen in a predictable order.
+func bundleArtifacts(d bundleReader, config *Config) ([]*bundleArtifact, error) {
+	dir := localPath(config, d.Get("directory").(string))
+	var artifacts []*bundleArtifact
+	seen := make(map[string]bool)
+	for _, v := range d.Get("artifact").(*schema.Set).List() {
+		m := v.(map[string]interface{})
+		a := &bundleArtifact{
+			name:     m["name"].(string),
+			version:  m["version"].(string),
+			jarPath:  filepath.Join(dir, m["jar"].(string)),
+			confPath: filepath.Join(dir, m["json_config"].(string)),
+		}
+		if seen[a.key()] {
+			return nil, fmt.Errorf("artifact %q is listed more than once", a.key())
+		}
+		seen[a.key()] = true
+		artifacts = append(artifacts, a)
+	}
+	sort.Slice(artifacts, func(i, j int) bool { return artifacts[i].key() < artifacts[j].key() })
+	return artifacts, nil
+}
+
+// resourceLocalArtifactBundleCustomizeDiff plans an update when the uploaded
+// artifacts no longer match the bundle, either because artifacts were added
+// or removed, or because files on disk changed.
+func resourceLocalArtifactBundleCustomizeDiff(ctx context.Context, d *schema.ResourceDiff, m interface{}) error {
+	if d.Id() == "" || !d.NewValueKnown("directory") || !d.NewValueKnown("artifact") {
+		return nil
+	}
+	config, _ := m.(*Config)
+	artifacts, err := bundleArtifacts(d, config)
+	if err != nil {
+		return err
+	}
+
+	want := make(map[string]interface{})
+	for _, a := range artifacts {
+		sum, err := a.checksum()
+		if err != nil {
+			// The file may be generated later in the apply.
+			log.Printf("[DEBUG] skipping plan time checksum of artifact %q: %v", a.key(), err)
+		}
+		want[a.key()] = sum
+	}
+	if reflect.DeepEqual(want, d.Get("uploaded").(map[string]interface{})) {
+		return nil
+	}
+	return d.SetNewComputed("uploaded")
+}
+
+func resourceLocalArtifactBundleCreate(d *schema.ResourceData, m interface{}) error {
+	config := m.(*Config)
+	if err := setDefaultNamespace(d, config); err != nil {
+		return err
+	}
+	d.SetId(d.Get("namespace").(string) + "/" + d.Get("directory").(string))
+
+	if err := syncArtifactBundle(d, config, nil, d.Timeout(schema.TimeoutCreate)); err != nil {
+		return fmt.Errorf("%v (the artifacts uploaded so far are kept in state, run terraform untaint to only upload the remaining ones)", err)
+	}
+	return nil
+}
+
+func resourceLocalArtifactBundleUpdate(d *schema.ResourceData, m interface{}) error {
+	config := m.(*Config)
+	old, _ := d.GetChange("uploaded")
+	return syncArtifactBundle(d, config, old.(map[string]interface{}), d.Timeout(schema.TimeoutUpdate))
+}
+
+// syncArtifactBundle uploads the artifacts of the bundle that are not in
+// uploaded yet or whose files changed, then deletes the ones removed from the
+// bundle. It stops at the first failed upload and records what was uploaded
+// until then, so the next apply picks up where it stopped.
+func syncArtifactBundle(d *schema.ResourceData, config *Config, uploaded map[string]interface{}, timeout time.Duration) error {
+	namespace := d.Get("namespace").(string)
+	state := make(map[string]interface{}, len(uploaded))
+	for k, v := range uploaded {
+		state[k] = v
+	}
+	defer func() {
+		d.Set("uploaded", state)
+	}()
+
+	artifacts, err := bundleArtifacts(d, config)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(config.httpClient.stopCtx, timeout)
+	defer cancel()
+
+	want := make(map[string]bool)
+	for _, ba := range artifacts {
+		want[ba.key()] = true
+		sum, err := ba.checksum()
+		if err != nil {
+			return fmt.Errorf("failed to read files of artifact %q: %v", ba.key(), err)
+		}
+		if state[ba.key()] == sum {
+			continue
+		}
+
+		a, err := loadArtifactFiles(config, ba.name, ba.version, ba.jarPath, ba.confPath)
+		if err != nil {
+			return err
+		}
+		addr := urlJoin(config.host, "/v3/namespaces", namespace, "/artifacts", a.name)
+		if err := uploadJar(ctx, config, addr, a); err != nil {
+			return fmt.Errorf("failed to upload artifact %q: %v", ba.key(), impersonationError(config, namespace, err))
+		}
+		// Without its properties the artifact is incomplete, so it is only
+		// recorded once they are set.
+		if err := uploadProps(ctx, config.httpClient, addr, a); err != nil {
+			return fmt.Errorf("failed to set properties of artifact %q: %v", ba.key(), err)
+		}
+		state[ba.key()] = sum
+	}
+
+	var failed []string
+	for key := range state {
+		if want[key] {
+			continue
+		}
+		if err := deleteBundleArtifact(ctx, config, namespace, key); err != nil {
+			failed = append(failed, err.Error())
+			continue
+		}
+		delete(state, key)
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("failed to delete artifacts removed from the bundle: %v", strings.Join(failed, "; "))
+	}
+	return nil
+}
+
+// deleteBundleArtifact deletes the artifact version with the given
+// name/version key, treating already deleted artifacts as deleted.
+func deleteBundleArtifact(ctx context.Context, config *Config, namespace, key string) error {
+	parts := strings.SplitN(key, "/", 2)
+	if len(parts) != 2 {
+		return fmt.Errorf("invalid artifact key %q, want name/version", key)
+	}
+	addr := urlJoin(config.host, "/v3/namespaces", namespace, "/artifacts", parts[0], "/versions", parts[1])
+	err := deleteArtifactVersion(ctx, config.httpClient, addr)
+	if isNotFound(err) {
+		log.Printf("artifact %q already deleted", key)
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("artifact %q: %v", key, err)
+	}
+	return nil
+}
+
+func resourceLocalArtifactBundleRead(d *schema.ResourceData, m interface{}) error {
+	config := m.(*Config)
+	namespace := d.Get("namespace").(string)
+
+	// Artifacts deleted outside of Terraform are dropped, so they are uploaded again.
+	uploaded := make(map[string]interface{})
+	if exists, err := namespaceExists(config, namespace); err != nil {
+		return fmt.Errorf("failed to check for existence of namespace %q: %v", namespace, err)
+	} else if !exists {
+		d.Set("uploaded", uploaded)
+		return nil
+	}
+
+	for key, sum := range d.Get("uploaded").(map[string]interface{}) {
+		parts := strings.SplitN(key, "/", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		found, err := artifactVersionExists(config, namespace, parts[0], parts[1], "user")
+		if err != nil {
+			return err
+		}
+		if !found {
+			log.Printf("artifact %q not found, removing from state", key)
+			continue
+		}
+		uploaded[key] = sum
+	}
+	d.Set("uploaded", uploaded)
+	return nil
+}
+
+func resourceLocalArtifactBundleDelete(d *schema.ResourceData, m interface{}) error {
+	config := m.(*Config)
+	namespace := d.Get("namespace").(string)
+
+	ctx, cancel := context.WithTimeout(config.httpClient.stopCtx, d.Timeout(schema.TimeoutDelete))
+	defer cancel()
+
+	// Keep the artifacts that could not be deleted in state, so deleting
+	// them is retried.
+	remaining := make(map[string]interface{})
+	var failed []string
+	for key, sum := range d.Get("uploaded").(map[string]interface{}) {
+		if err := deleteBundleArtifact(ctx, config, namespace, key); err != nil {
+			failed = append(failed, err.Error())
+			remaining[key] = sum
+		}
+	}
+	if len(failed) > 0 {
+		d.Set("uploaded", remaining)
+		return fmt.Errorf("failed to delete artifacts: %v", strings.Join(failed, "; "))
+	}
+	return nil
+}
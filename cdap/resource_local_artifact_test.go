@@ -0,0 +1,549 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cdap
+
+import (
+	"archive/zip"
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
+)
+
+func TestPropertiesDelta(t *testing.T) {
+	tests := []struct {
+		name        string
+		old         map[string]interface{}
+		props       map[string]string
+		wantRemoved []string
+		wantChanged []string
+	}{
+		{
+			name:  "unchanged",
+			old:   map[string]interface{}{"a": "1", "b": "2"},
+			props: map[string]string{"a": "1", "b": "2"},
+		},
+		{
+			name:        "changed",
+			old:         map[string]interface{}{"a": "1", "b": "2"},
+			props:       map[string]string{"a": "1", "b": "3"},
+			wantChanged: []string{"b"},
+		},
+		{
+			name:        "added",
+			old:         map[string]interface{}{"a": "1"},
+			props:       map[string]string{"a": "1", "c": "3", "b": "2"},
+			wantChanged: []string{"b", "c"},
+		},
+		{
+			name:        "removed",
+			old:         map[string]interface{}{"a": "1", "b": "2", "c": "3"},
+			props:       map[string]string{"b": "2"},
+			wantRemoved: []string{"a", "c"},
+		},
+		{
+			name:        "all at once",
+			old:         map[string]interface{}{"keep": "1", "change": "old", "gone": "x"},
+			props:       map[string]string{"keep": "1", "change": "new", "added": "v"},
+			wantRemoved: []string{"gone"},
+			wantChanged: []string{"added", "change"},
+		},
+		{
+			name:        "from empty",
+			props:       map[string]string{"a": "1"},
+			wantChanged: []string{"a"},
+		},
+	}
+	for _, tt := range tests {
+		removed, changed := propertiesDelta(tt.old, tt.props)
+		if !reflect.DeepEqual(removed, tt.wantRemoved) || !reflect.DeepEqual(changed, tt.wantChanged) {
+			t.Errorf("%s: propertiesDelta() = %q, %q, want %q, %q", tt.name, removed, changed, tt.wantRemoved, tt.wantChanged)
+		}
+	}
+}
+
+func TestUpdatePropertiesIncrementally(t *testing.T) {
+	addr := "/v3/namespaces/default/artifacts/example/versions/1.0.0/properties/"
+	tests := []struct {
+		name  string
+		state map[string]string
+		props map[string]string
+		want  []string
+	}{
+		{
+			name:  "mixed",
+			state: map[string]string{"keep": "1", "change": "old", "gone/key": "x"},
+			props: map[string]string{"keep": "1", "change": "new", "added": "v"},
+			want: []string{
+				"DELETE " + addr + "gone%2Fkey ",
+				"PUT " + addr + "added v",
+				"PUT " + addr + "change new",
+			},
+		},
+		{
+			name:  "removed only",
+			state: map[string]string{"a": "1", "b": "2"},
+			props: map[string]string{"a": "1"},
+			want:  []string{"DELETE " + addr + "b "},
+		},
+		{
+			name:  "unchanged",
+			state: map[string]string{"a": "1"},
+			props: map[string]string{"a": "1"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls []string
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				b, err := ioutil.ReadAll(r.Body)
+				if err != nil {
+					t.Error(err)
+				}
+				calls = append(calls, r.Method+" "+r.URL.EscapedPath()+" "+string(b))
+			}))
+			defer srv.Close()
+
+			config := &Config{host: srv.URL, httpClient: &apiClient{Client: srv.Client(), stopCtx: context.Background()}}
+			attrs := map[string]string{
+				"name":                   "example",
+				"version":                "1.0.0",
+				"namespace":              "default",
+				"scope":                  "user",
+				"properties_incremental": "true",
+				"properties.%":           strconv.Itoa(len(tt.state)),
+			}
+			for k, v := range tt.state {
+				attrs["properties."+k] = v
+			}
+			d := resourceLocalArtifact().Data(&terraform.InstanceState{ID: "example", Attributes: attrs})
+			if err := updatePropertiesIncrementally(d, config, tt.props); err != nil {
+				t.Fatalf("updatePropertiesIncrementally returned error: %v", err)
+			}
+			if !reflect.DeepEqual(calls, tt.want) {
+				t.Errorf("updatePropertiesIncrementally made calls %q, want %q", calls, tt.want)
+			}
+		})
+	}
+}
+
+func TestLocalArtifactReadPropertiesDrift(t *testing.T) {
+	config := newTestConfig(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet || r.URL.Path != "/v3/namespaces/default/artifacts/example/versions/1.0.0" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL)
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		// The properties were changed outside of Terraform.
+		w.Write([]byte(`{"name": "example", "version": "1.0.0", "scope": "USER", "properties": {"a": "changed", "b": "added"}}`))
+	})
+	d := resourceLocalArtifact().Data(&terraform.InstanceState{ID: "example", Attributes: map[string]string{
+		"name":         "example",
+		"version":      "1.0.0",
+		"namespace":    "default",
+		"scope":        "user",
+		"properties.%": "1",
+		"properties.a": "1",
+	}})
+	if err := resourceLocalArtifactRead(d, config); err != nil {
+		t.Fatalf("Read returned error: %v", err)
+	}
+	if d.Id() == "" {
+		t.Fatal("Read removed the artifact from state")
+	}
+	want := map[string]interface{}{"a": "changed", "b": "added"}
+	if got := d.Get("properties"); !reflect.DeepEqual(got, want) {
+		t.Errorf("properties after Read = %v, want %v", got, want)
+	}
+}
+
+// An artifact whose properties cannot be set is kept in state without being
+// tainted, so the next apply only sets the properties.
+func TestLocalArtifactCreatePropertiesFailure(t *testing.T) {
+	dir := t.TempDir()
+	jarPath := filepath.Join(dir, "example-1.0.0.jar")
+	f, err := os.Create(jarPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	zw := zip.NewWriter(f)
+	if _, err := zw.Create("Example.class"); err != nil {
+		t.Fatal(err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+	confPath := filepath.Join(dir, "example-1.0.0.json")
+	if err := ioutil.WriteFile(confPath, []byte(`{"properties": {"a": "1"}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	config := newTestConfig(t, func(w http.ResponseWriter, r *http.Request) {
+		base := "/v3/namespaces/default/artifacts/example"
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == base:
+			w.Write([]byte("[]"))
+		case r.Method == http.MethodPut && r.URL.Path == base+"/versions/1.0.0/properties":
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte("boom"))
+		case r.Method == http.MethodGet && r.URL.Path == base+"/versions/1.0.0":
+			w.Write([]byte(`{"name": "example", "version": "1.0.0", "scope": "USER", "properties": {}}`))
+		}
+	})
+	d := schema.TestResourceDataRaw(t, resourceLocalArtifact().Schema, map[string]interface{}{
+		"name":             "example",
+		"namespace":        "default",
+		"version":          "1.0.0",
+		"jar_binary_path":  jarPath,
+		"json_config_path": confPath,
+	})
+	diags := resourceLocalArtifactCreateContext(context.Background(), d, config)
+	if len(diags) != 1 || diags[0].Severity != diag.Warning {
+		t.Fatalf("create returned %+v, want a single warning", diags)
+	}
+	if d.Id() != "example" {
+		t.Errorf("ID = %q, want %q", d.Id(), "example")
+	}
+	if got := d.Get("json_config_sha256"); got != "" {
+		t.Errorf("json_config_sha256 = %q, want it cleared so the next plan updates the properties", got)
+	}
+	if got := d.Get("properties").(map[string]interface{}); len(got) != 0 {
+		t.Errorf("properties = %v, want the ones CDAP has", got)
+	}
+}
+
+func TestLocalArtifactDeleteAllVersions(t *testing.T) {
+	var calls []string
+	config := newTestConfig(t, func(w http.ResponseWriter, r *http.Request) {
+		calls = append(calls, r.Method+" "+r.URL.Path)
+		if r.Method == http.MethodGet {
+			w.Write([]byte(`[{"name": "example", "version": "1.0.0"}, {"name": "example", "version": "1.1.0"}, {"name": "example", "version": "2.0.0"}]`))
+		}
+	})
+	d := resourceLocalArtifact().Data(&terraform.InstanceState{ID: "example", Attributes: map[string]string{
+		"name":                "example",
+		"version":             "2.0.0",
+		"namespace":           "default",
+		"scope":               "user",
+		"delete_all_versions": "true",
+	}})
+	if err := resourceLocalArtifactDelete(d, config); err != nil {
+		t.Fatalf("Delete returned error: %v", err)
+	}
+
+	addr := "/v3/namespaces/default/artifacts/example"
+	want := []string{
+		"GET " + addr,
+		"DELETE " + addr + "/versions/1.0.0",
+		"DELETE " + addr + "/versions/1.1.0",
+		"DELETE " + addr + "/versions/2.0.0",
+	}
+	if !reflect.DeepEqual(calls, want) {
+		t.Errorf("Delete made calls %q, want %q", calls, want)
+	}
+}
+
+// Deleting an artifact that is already gone succeeds, since it is the
+// desired end state.
+func TestLocalArtifactDeleteNotFound(t *testing.T) {
+	tests := []struct {
+		name    string
+		code    int
+		wantErr bool
+	}{
+		{name: "deleted", code: http.StatusOK},
+		{name: "already deleted", code: http.StatusNotFound},
+		{name: "server error", code: http.StatusInternalServerError, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := newTestConfig(t, func(w http.ResponseWriter, r *http.Request) {
+				if r.Method != http.MethodDelete || r.URL.Path != "/v3/namespaces/default/artifacts/example/versions/1.0.0" {
+					t.Errorf("unexpected request %s %s", r.Method, r.URL)
+				}
+				w.WriteHeader(tt.code)
+			})
+			d := resourceLocalArtifact().Data(&terraform.InstanceState{ID: "example", Attributes: map[string]string{
+				"name":      "example",
+				"version":   "1.0.0",
+				"namespace": "default",
+				"scope":     "user",
+			}})
+			err := resourceLocalArtifactDelete(d, config)
+			if gotErr := err != nil; gotErr != tt.wantErr {
+				t.Errorf("Delete returned error %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// Properties explicitly set to an empty map are tracked in state, so
+// properties added on the instance show up as drift, while properties that
+// are not set at all are not tracked.
+func TestLocalArtifactReadEmptyProperties(t *testing.T) {
+	tests := []struct {
+		name  string
+		attrs map[string]string
+		want  map[string]interface{}
+	}{
+		{name: "empty map", attrs: map[string]string{"properties.%": "0"}, want: map[string]interface{}{"added": "v"}},
+		{name: "unset", want: map[string]interface{}{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := newTestConfig(t, func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte(`{"name": "example", "version": "1.0.0", "scope": "USER", "properties": {"added": "v"}}`))
+			})
+			attrs := map[string]string{
+				"name":      "example",
+				"version":   "1.0.0",
+				"namespace": "default",
+				"scope":     "user",
+			}
+			for k, v := range tt.attrs {
+				attrs[k] = v
+			}
+			r := resourceLocalArtifact()
+			state := &terraform.InstanceState{ID: "example", Attributes: attrs}
+			raw, err := state.AttrsAsObjectValue(r.CoreConfigSchema().ImpliedType())
+			if err != nil {
+				t.Fatal(err)
+			}
+			state.RawState = raw
+			d := r.Data(state)
+			if err := resourceLocalArtifactRead(d, config); err != nil {
+				t.Fatalf("Read returned error: %v", err)
+			}
+			if got := d.Get("properties"); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("properties after Read = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// By default updates replace all properties of the artifact, while with
+// properties_merge the properties not managed by the resource are kept.
+func TestLocalArtifactUpdatePropertiesMerge(t *testing.T) {
+	tests := []struct {
+		name     string
+		merge    bool
+		instance string
+		want     string
+	}{
+		{name: "replace", instance: `{"a": "1", "b": "2"}`, want: `{"a":"changed"}`},
+		{name: "merge", merge: true, instance: `{"a": "1", "b": "2", "other": "x"}`, want: `{"a":"changed","other":"x"}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var sent []string
+			config := newTestConfig(t, func(w http.ResponseWriter, r *http.Request) {
+				switch r.Method {
+				case http.MethodGet:
+					w.Write([]byte(`{"name": "example", "version": "1.0.0", "scope": "USER", "properties": ` + tt.instance + `}`))
+				case http.MethodPut:
+					b, _ := ioutil.ReadAll(r.Body)
+					sent = append(sent, string(b))
+				default:
+					t.Errorf("unexpected request %s %s", r.Method, r.URL)
+				}
+			})
+			d := updatedLocalArtifact(t, map[string]string{
+				"properties_merge": strconv.FormatBool(tt.merge),
+				"properties.%":     "2",
+				"properties.a":     "1",
+				"properties.b":     "2",
+			}, map[string]interface{}{
+				"properties_merge": tt.merge,
+				"properties":       map[string]interface{}{"a": "changed"},
+			})
+			if err := resourceLocalArtifactUpdate(d, config); err != nil {
+				t.Fatalf("Update returned error: %v", err)
+			}
+			if want := []string{tt.want}; !reflect.DeepEqual(sent, want) {
+				t.Errorf("Update sent properties %q, want %q", sent, want)
+			}
+		})
+	}
+}
+
+// updatedLocalArtifact returns the data of an update of a cdap_local_artifact
+// from the given state attributes to the given config. Both are added to a
+// minimal artifact with an inline JSON config.
+func updatedLocalArtifact(t *testing.T, attrs map[string]string, raw map[string]interface{}) *schema.ResourceData {
+	t.Helper()
+	jarPath := filepath.Join(t.TempDir(), "example-1.0.0.jar")
+	if err := ioutil.WriteFile(jarPath, []byte("example jar"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	r := resourceLocalArtifact()
+	state := &terraform.InstanceState{ID: "example", Attributes: map[string]string{
+		"name":            "example",
+		"version":         "1.0.0",
+		"namespace":       "default",
+		"scope":           "user",
+		"jar_binary_path": jarPath,
+		"json_config":     "{}",
+	}}
+	for k, v := range attrs {
+		state.Attributes[k] = v
+	}
+	conf := map[string]interface{}{
+		"name":            "example",
+		"version":         "1.0.0",
+		"namespace":       "default",
+		"jar_binary_path": jarPath,
+		"json_config":     "{}",
+	}
+	for k, v := range raw {
+		conf[k] = v
+	}
+	diff, err := r.Diff(context.Background(), state, terraform.NewResourceConfigRaw(conf), &Config{})
+	if err != nil {
+		t.Fatalf("Diff returned error: %v", err)
+	}
+	d, err := schema.InternalMap(r.Schema).Data(state, diff)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return d
+}
+
+func TestArtifactProperties(t *testing.T) {
+	conf := &artifactConfig{Properties: map[string]string{"a": "file", "b": "file"}}
+	tests := []struct {
+		name   string
+		source string
+		inline map[string]interface{}
+		want   map[string]string
+	}{
+		{name: "file", source: "file", want: map[string]string{"a": "file", "b": "file"}},
+		{name: "inline", source: "inline", inline: map[string]interface{}{"a": "inline"}, want: map[string]string{"a": "inline"}},
+		{name: "inline without properties", source: "inline", want: map[string]string{"a": "file", "b": "file"}},
+		{name: "merge", source: "merge", inline: map[string]interface{}{"a": "inline", "c": "inline"}, want: map[string]string{"a": "inline", "b": "file", "c": "inline"}},
+		{name: "merge without properties", source: "merge", want: map[string]string{"a": "file", "b": "file"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			raw := map[string]interface{}{
+				"name":              "example",
+				"version":           "1.0.0",
+				"jar_binary_path":   "example-1.0.0.jar",
+				"properties_source": tt.source,
+			}
+			if tt.inline != nil {
+				raw["properties"] = tt.inline
+			}
+			d := schema.TestResourceDataRaw(t, resourceLocalArtifact().Schema, raw)
+			if got := artifactProperties(d, conf); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("artifactProperties() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// Changes of properties update the artifact in place, while changes of the
+// parents uploaded with the JAR replace it.
+func TestLocalArtifactDiff(t *testing.T) {
+	const oldConfig = `{"parents":["system:cdap-data-pipeline[6.0.0,7.0.0)"],"properties":{"a":"1"}}`
+	tests := []struct {
+		name          string
+		jsonConfig    string
+		properties    map[string]interface{}
+		wantForceNew  bool
+		wantNoChanges bool
+	}{
+		{name: "unchanged", jsonConfig: oldConfig, wantNoChanges: true},
+		{name: "properties attribute", jsonConfig: oldConfig, properties: map[string]interface{}{"a": "2"}},
+		{name: "properties in the JSON config", jsonConfig: `{"parents": ["system:cdap-data-pipeline[6.0.0,7.0.0)"], "properties": {"a": "2"}}`},
+		{name: "parents", jsonConfig: `{"parents": ["system:cdap-data-pipeline[6.0.0,8.0.0)"], "properties": {"a": "1"}}`, wantForceNew: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			jarPath := filepath.Join(t.TempDir(), "example-1.0.0.jar")
+			if err := ioutil.WriteFile(jarPath, []byte("example jar"), 0644); err != nil {
+				t.Fatal(err)
+			}
+			jarSum, err := fileSHA256(jarPath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			conf, err := parseArtifactConfig([]byte(oldConfig), "json_config")
+			if err != nil {
+				t.Fatal(err)
+			}
+			uploadSum, err := uploadConfigSHA256(conf)
+			if err != nil {
+				t.Fatal(err)
+			}
+			state := &terraform.InstanceState{ID: "example", Attributes: map[string]string{
+				"name":                      "example",
+				"version":                   "1.0.0",
+				"namespace":                 "default",
+				"scope":                     "user",
+				"jar_binary_path":           jarPath,
+				"jar_sha256":                jarSum,
+				"json_config":               oldConfig,
+				"json_config_upload_sha256": uploadSum,
+				"properties_source":         "inline",
+				"properties_merge":          "false",
+				"properties_incremental":    "false",
+				"verify_upload":             "true",
+				"verify_integrity":          "false",
+				"immutable":                 "false",
+				"create_namespace":          "false",
+				"skip_namespace_check":      "false",
+				"skip_file_check":           "false",
+				"delete_all_versions":       "false",
+				"plugin_classes.#":          "0",
+			}}
+			raw := map[string]interface{}{
+				"name":            "example",
+				"version":         "1.0.0",
+				"namespace":       "default",
+				"jar_binary_path": jarPath,
+				"json_config":     tt.jsonConfig,
+			}
+			if tt.properties != nil {
+				raw["properties"] = tt.properties
+			}
+			diff, err := resourceLocalArtifact().Diff(context.Background(), state, terraform.NewResourceConfigRaw(raw), &Config{})
+			if err != nil {
+				t.Fatalf("Diff returned error: %v", err)
+			}
+			if tt.wantNoChanges {
+				if diff != nil && !diff.Empty() {
+					t.Errorf("Diff = %v, want no changes", diff)
+				}
+				return
+			}
+			if diff == nil || diff.Empty() {
+				t.Fatal("Diff returned no changes")
+			}
+			if got := diff.RequiresNew(); got != tt.wantForceNew {
+				t.Errorf("Diff requires a new artifact = %v, want %v, diff: %v", got, tt.wantForceNew, diff)
+			}
+		})
+	}
+}
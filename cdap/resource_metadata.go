@@ -0,0 +1,275 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cdap
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+)
+
+// resourceMetadata manages user metadata of an entity. Only the tags and
+// properties set through the resource are tracked, so metadata added by
+// other tools is left untouched.
+// https://docs.cdap.io/cdap/current/en/reference-manual/http-restful-api/metadata.html
+func resourceMetadata() *schema.Resource {
+	return &schema.Resource{
+		Create: resourceMetadataCreate,
+		Read:   resourceMetadataRead,
+		Update: resourceMetadataUpdate,
+		Delete: resourceMetadataDelete,
+
+		Schema: map[string]*schema.Schema{
+			"namespace": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Computed:    true,
+				ForceNew:    true,
+				Description: "The name of the namespace in which the entity belongs. If not provided, the provider's default_namespace is used.",
+			},
+			"entity_type": {
+				Type:         schema.TypeString,
+				Required:     true,
+				ForceNew:     true,
+				ValidateFunc: validation.StringInSlice([]string{"apps", "artifacts", "datasets"}, false),
+				Description:  "The type of the entity, one of apps, artifacts or datasets.",
+			},
+			"entity": {
+				Type:        schema.TypeString,
+				Required:    true,
+				ForceNew:    true,
+				Description: "The name of the entity.",
+			},
+			"entity_version": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				ForceNew:    true,
+				Description: "The version of the entity. Required for artifacts.",
+			},
+			"tags": {
+				Type:        schema.TypeSet,
+				Optional:    true,
+				Description: "The tags to add to the entity.",
+				Elem:        &schema.Schema{Type: schema.TypeString},
+			},
+			"properties": {
+				Type:        schema.TypeMap,
+				Optional:    true,
+				Description: "The properties to add to the entity.",
+				Elem:        &schema.Schema{Type: schema.TypeString},
+			},
+		},
+	}
+}
+
+func metadataAddr(config *Config, d *schema.ResourceData) (string, error) {
+	entityType := d.Get("entity_type").(string)
+	paths := []string{"/v3/namespaces", d.Get("namespace").(string), entityType, d.Get("entity").(string)}
+	if entityType == "artifacts" {
+		version := d.Get("entity_version").(string)
+		if version == "" {
+			return "", fmt.Errorf("entity_version must be set for artifacts")
+		}
+		paths = append(paths, "/versions", version)
+	}
+	return urlJoin(config.host, append(paths, "/metadata")...), nil
+}
+
+func resourceMetadataCreate(d *schema.ResourceData, m interface{}) error {
+	config := m.(*Config)
+	if err := setDefaultNamespace(d, config); err != nil {
+		return err
+	}
+	addr, err := metadataAddr(config, d)
+	if err != nil {
+		return err
+	}
+
+	if err := addMetadata(config, addr, setToStrings(d.Get("tags").(*schema.Set)), d.Get("properties").(map[string]interface{})); err != nil {
+		return err
+	}
+
+	d.SetId(strings.TrimPrefix(addr, urlJoin(config.host, "/v3/namespaces")+"/"))
+	return nil
+}
+
+func setToStrings(s *schema.Set) []string {
+	var strs []string
+	for _, v := range s.List() {
+		strs = append(strs, v.(string))
+	}
+	return strs
+}
+
+// addMetadata adds the tags and properties to the entity, keeping the ones
+// that already exist.
+func addMetadata(config *Config, addr string, tags []string, props map[string]interface{}) error {
+	if len(tags) > 0 {
+		if err := postMetadata(config, urlJoin(addr, "/tags"), tags); err != nil {
+			return err
+		}
+	}
+	if len(props) > 0 {
+		if err := postMetadata(config, urlJoin(addr, "/properties"), props); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func postMetadata(config *Config, addr string, v interface{}) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, addr, bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	_, err = httpCall(config.httpClient, req)
+	return err
+}
+
+// removeMetadata removes the tags and properties with the given keys from the entity.
+func removeMetadata(config *Config, addr string, tags []string, keys []string) error {
+	for _, t := range tags {
+		if err := deleteMetadata(config, urlJoin(addr, "/tags", t)); err != nil {
+			return err
+		}
+	}
+	for _, k := range keys {
+		if err := deleteMetadata(config, urlJoin(addr, "/properties", k)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func deleteMetadata(config *Config, addr string) error {
+	req, err := http.NewRequest(http.MethodDelete, addr, nil)
+	if err != nil {
+		return err
+	}
+	_, err = httpCall(config.httpClient, req)
+	return err
+}
+
+func resourceMetadataRead(d *schema.ResourceData, m interface{}) error {
+	config := m.(*Config)
+	addr, err := metadataAddr(config, d)
+	if err != nil {
+		return err
+	}
+
+	var tags []string
+	if err := getMetadata(config, urlJoin(addr, "/tags"), &tags); isNotFound(err) {
+		log.Printf("entity %q not found, removing from state", d.Id())
+		d.SetId("")
+		return nil
+	} else if err != nil {
+		return err
+	}
+	props := make(map[string]string)
+	if err := getMetadata(config, urlJoin(addr, "/properties"), &props); err != nil {
+		return err
+	}
+
+	// Only report the metadata managed by this resource.
+	managedTags := d.Get("tags").(*schema.Set)
+	var gotTags []string
+	for _, t := range tags {
+		if managedTags.Contains(t) {
+			gotTags = append(gotTags, t)
+		}
+	}
+	gotProps := make(map[string]string)
+	for k := range d.Get("properties").(map[string]interface{}) {
+		if v, ok := props[k]; ok {
+			gotProps[k] = v
+		}
+	}
+
+	d.Set("tags", gotTags)
+	d.Set("properties", gotProps)
+	return nil
+}
+
+func getMetadata(config *Config, addr string, v interface{}) error {
+	req, err := http.NewRequest(http.MethodGet, addr+"?scope=USER", nil)
+	if err != nil {
+		return err
+	}
+	b, err := httpCall(config.httpClient, req)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, v)
+}
+
+func resourceMetadataUpdate(d *schema.ResourceData, m interface{}) error {
+	config := m.(*Config)
+	addr, err := metadataAddr(config, d)
+	if err != nil {
+		return err
+	}
+
+	oldTags, newTags := d.GetChange("tags")
+	removedTags := setToStrings(oldTags.(*schema.Set).Difference(newTags.(*schema.Set)))
+	addedTags := setToStrings(newTags.(*schema.Set).Difference(oldTags.(*schema.Set)))
+
+	oldProps, newProps := d.GetChange("properties")
+	var removedKeys []string
+	for k := range oldProps.(map[string]interface{}) {
+		if _, ok := newProps.(map[string]interface{})[k]; !ok {
+			removedKeys = append(removedKeys, k)
+		}
+	}
+	changedProps := make(map[string]interface{})
+	for k, v := range newProps.(map[string]interface{}) {
+		if old, ok := oldProps.(map[string]interface{})[k]; !ok || old != v {
+			changedProps[k] = v
+		}
+	}
+
+	if err := removeMetadata(config, addr, removedTags, removedKeys); err != nil {
+		return err
+	}
+	return addMetadata(config, addr, addedTags, changedProps)
+}
+
+func resourceMetadataDelete(d *schema.ResourceData, m interface{}) error {
+	config := m.(*Config)
+	addr, err := metadataAddr(config, d)
+	if err != nil {
+		return err
+	}
+
+	var keys []string
+	for k := range d.Get("properties").(map[string]interface{}) {
+		keys = append(keys, k)
+	}
+	err = removeMetadata(config, addr, setToStrings(d.Get("tags").(*schema.Set)), keys)
+	if isNotFound(err) {
+		// The entity was deleted along with its metadata.
+		return nil
+	}
+	return err
+}
@@ -15,8 +15,16 @@
 package cdap
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
 	"net/http"
+	"strings"
+	"time"
 
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
 )
@@ -26,6 +34,7 @@ func resourceNamespace() *schema.Resource {
 	return &schema.Resource{
 		Create: resourceNamespaceCreate,
 		Read:   resourceNamespaceRead,
+		Update: resourceNamespaceUpdate,
 		Delete: resourceNamespaceDelete,
 		Exists: resourceNamespaceExists,
 
@@ -36,30 +45,184 @@ func resourceNamespace() *schema.Resource {
 				ForceNew:    true,
 				Description: "The name of the namespace.",
 			},
+			"description": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Description: "A description of the namespace.",
+			},
+			"scheduler_queue_name": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Description: "The name of the scheduler queue programs in the namespace are run in.",
+			},
+			"principal": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				ForceNew:     true,
+				RequiredWith: []string{"keytab_uri"},
+				Description:  "The Kerberos principal programs in the namespace are impersonated as. Must be set together with keytab_uri.",
+			},
+			"keytab_uri": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				ForceNew:     true,
+				RequiredWith: []string{"principal"},
+				Description:  "The URI of the keytab for the principal.",
+			},
+			"wait_until_ready": {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Default:     false,
+				Description: "Whether to wait after creating the namespace until CDAP serves it and its datasets, so artifacts uploaded right after do not race its initialization. The wait is bounded by the create timeout.",
+			},
+		},
+		Timeouts: &schema.ResourceTimeout{
+			Create: schema.DefaultTimeout(5 * time.Minute),
+			Delete: schema.DefaultTimeout(5 * time.Minute),
 		},
 	}
 }
 
+// namespaceMeta is the body of the CDAP namespace API.
+type namespaceMeta struct {
+	Name        string           `json:"name,omitempty"`
+	Description string           `json:"description"`
+	Config      *namespaceConfig `json:"config,omitempty"`
+}
+
+type namespaceConfig struct {
+	SchedulerQueueName string `json:"scheduler.queue.name,omitempty"`
+	Principal          string `json:"principal,omitempty"`
+	KeytabURI          string `json:"keytabURI,omitempty"`
+}
+
 func resourceNamespaceCreate(d *schema.ResourceData, m interface{}) error {
 	config := m.(*Config)
 	name := d.Get("name").(string)
+	if err := createNamespace(config, name, namespaceMetaFromResource(d)); err != nil {
+		return err
+	}
+	d.SetId(name)
+
+	if d.Get("wait_until_ready").(bool) {
+		ctx, cancel := context.WithTimeout(config.httpClient.stopCtx, d.Timeout(schema.TimeoutCreate))
+		defer cancel()
+		if err := waitForNamespace(ctx, config, name); err != nil {
+			return fmt.Errorf("namespace %q was created but did not become ready: %v", name, err)
+		}
+	}
+	return nil
+}
+
+// waitForNamespace waits until the namespace and its datasets can be read.
+// CDAP reports no state for namespaces, so serving their datasets is taken as
+// sign that their storage is initialized.
+func waitForNamespace(ctx context.Context, config *Config, name string) error {
+	nsAddr := urlJoin(config.host, "/v3/namespaces", name)
+	return pollUntil(ctx, time.Second, func() (bool, error) {
+		for _, addr := range []string{nsAddr, urlJoin(nsAddr, "/data/datasets")} {
+			req, err := http.NewRequestWithContext(ctx, http.MethodGet, addr, nil)
+			if err != nil {
+				return false, err
+			}
+			if _, err := httpCall(config.httpClient, req); isNotFound(err) {
+				return false, nil
+			} else if err != nil {
+				return false, err
+			}
+		}
+		return true, nil
+	})
+}
+
+func namespaceMetaFromResource(d *schema.ResourceData) *namespaceMeta {
+	return &namespaceMeta{
+		Description: d.Get("description").(string),
+		Config: &namespaceConfig{
+			SchedulerQueueName: d.Get("scheduler_queue_name").(string),
+			Principal:          d.Get("principal").(string),
+			KeytabURI:          d.Get("keytab_uri").(string),
+		},
+	}
+}
+
+// createNamespace creates the namespace. If meta is nil, CDAP's defaults are used.
+func createNamespace(config *Config, name string, meta *namespaceMeta) error {
 	addr := urlJoin(config.host, "/v3/namespaces", name)
 
-	req, err := http.NewRequest(http.MethodPut, addr, nil)
+	var body io.Reader
+	if meta != nil {
+		b, err := json.Marshal(meta)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(b)
+	}
+
+	req, err := http.NewRequest(http.MethodPut, addr, body)
 	if err != nil {
 		return err
 	}
 
-	if _, err := httpCall(config.httpClient, req); err != nil {
+	_, err = httpCall(config.httpClient, req)
+	return err
+}
+
+func resourceNamespaceRead(d *schema.ResourceData, m interface{}) error {
+	config := m.(*Config)
+	name := d.Get("name").(string)
+	addr := urlJoin(config.host, "/v3/namespaces", name)
+
+	req, err := http.NewRequest(http.MethodGet, addr, nil)
+	if err != nil {
 		return err
 	}
 
-	d.SetId(name)
+	b, err := httpCall(config.httpClient, req)
+	if isNotFound(err) {
+		log.Printf("namespace %q not found, removing from state", name)
+		d.SetId("")
+		return nil
+	}
+	if err != nil {
+		return permissionError(err, fmt.Sprintf("read namespace %q", name))
+	}
+
+	meta := new(namespaceMeta)
+	if err := json.Unmarshal(b, meta); err != nil {
+		return err
+	}
+	if meta.Config == nil {
+		meta.Config = new(namespaceConfig)
+	}
+
+	d.Set("description", meta.Description)
+	d.Set("scheduler_queue_name", meta.Config.SchedulerQueueName)
+	d.Set("principal", meta.Config.Principal)
+	d.Set("keytab_uri", meta.Config.KeytabURI)
 	return nil
 }
 
-func resourceNamespaceRead(d *schema.ResourceData, m interface{}) error {
-	return nil
+func resourceNamespaceUpdate(d *schema.ResourceData, m interface{}) error {
+	config := m.(*Config)
+	addr := urlJoin(config.host, "/v3/namespaces", d.Get("name").(string), "/properties")
+
+	meta := namespaceMetaFromResource(d)
+	// The principal and keytab cannot be changed after creation.
+	meta.Config.Principal = ""
+	meta.Config.KeytabURI = ""
+
+	b, err := json.Marshal(meta)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPut, addr, bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	_, err = httpCall(config.httpClient, req)
+	return err
 }
 
 func resourceNamespaceDelete(d *schema.ResourceData, m interface{}) error {
@@ -72,7 +235,42 @@ func resourceNamespaceDelete(d *schema.ResourceData, m interface{}) error {
 		return err
 	}
 	_, err = httpCall(config.httpClient, req)
-	return err
+
+	if hasRunningPrograms(err) {
+		return fmt.Errorf("failed to delete namespace %q, stop all programs running in it and try again: %v", name, err)
+	}
+	if err != nil {
+		return permissionError(err, fmt.Sprintf("delete namespace %q", name))
+	}
+
+	// The namespace is cleaned up asynchronously, and recreating it fails until it is gone.
+	ctx, cancel := context.WithTimeout(config.httpClient.stopCtx, d.Timeout(schema.TimeoutDelete))
+	defer cancel()
+	err = pollUntil(ctx, time.Second, func() (bool, error) {
+		exists, err := namespaceExists(config, name)
+		return !exists, err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to wait for deletion of namespace %q: %v", name, err)
+	}
+	return nil
+}
+
+// hasRunningPrograms reports whether err is CDAP refusing to delete a namespace
+// that still has running programs. CDAP replies with a 409, or with a 403 that
+// says so, which must not be mistaken for a token without access.
+func hasRunningPrograms(err error) bool {
+	var httpErr *httpError
+	if !errors.As(err, &httpErr) {
+		return false
+	}
+	switch httpErr.code {
+	case http.StatusConflict:
+		return true
+	case http.StatusForbidden:
+		return strings.Contains(strings.ToLower(errorMessage(httpErr.body)), "running")
+	}
+	return false
 }
 
 func resourceNamespaceExists(d *schema.ResourceData, m interface{}) (bool, error) {
@@ -96,7 +294,7 @@ func namespaceExists(config *Config, name string) (bool, error) {
 
 	b, err := httpCall(config.httpClient, req)
 	if err != nil {
-		return false, err
+		return false, permissionError(err, "list namespaces")
 	}
 
 	type namespace struct {
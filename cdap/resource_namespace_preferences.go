@@ -34,11 +34,9 @@ func resourceNamespacePreferences() *schema.Resource {
 			"namespace": {
 				Type:        schema.TypeString,
 				Optional:    true,
+				Computed:    true,
 				ForceNew:    true,
-				Description: "The name of the namespace in which this resource belongs. If not provided, the default namespace is used.",
-				DefaultFunc: func() (interface{}, error) {
-					return defaultNamespace, nil
-				},
+				Description: "The name of the namespace in which this resource belongs. If not provided, the provider's default_namespace is used.",
 			},
 			"preferences": {
 				Type:        schema.TypeMap,
@@ -55,6 +53,9 @@ func resourceNamespacePreferences() *schema.Resource {
 
 func resourceNamespacePreferencesCreate(d *schema.ResourceData, m interface{}) error {
 	config := m.(*Config)
+	if err := setDefaultNamespace(d, config); err != nil {
+		return err
+	}
 	namespace := d.Get("namespace").(string)
 	addr := urlJoin(config.host, "/v3/namespaces", namespace, "/preferences")
 
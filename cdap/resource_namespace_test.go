@@ -0,0 +1,51 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cdap
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+func TestNamespaceDeleteErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		code    int
+		body    string
+		wantErr string
+	}{
+		{name: "conflict", code: http.StatusConflict, body: "namespace is in use", wantErr: "stop all programs running in it"},
+		{name: "forbidden with running programs", code: http.StatusForbidden, body: `{"message":"Could not delete namespace 'team' since there are programs running in it"}`, wantErr: "stop all programs running in it"},
+		{name: "forbidden", code: http.StatusForbidden, body: "access denied", wantErr: "permission denied to delete namespace"},
+		{name: "unauthorized", code: http.StatusUnauthorized, body: "invalid token", wantErr: "permission denied to delete namespace"},
+		{name: "server error", code: http.StatusInternalServerError, body: "boom", wantErr: "failed with status 500"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := newTestConfig(t, func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.code)
+				w.Write([]byte(tt.body))
+			})
+			d := schema.TestResourceDataRaw(t, resourceNamespace().Schema, map[string]interface{}{"name": "team"})
+			err := resourceNamespaceDelete(d, config)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("resourceNamespaceDelete() = %v, want error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
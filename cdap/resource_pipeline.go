@@ -0,0 +1,311 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cdap
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/structure"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+)
+
+// pipelineArtifacts are the system artifacts pipelines of each type are
+// deployed from.
+var pipelineArtifacts = map[string]string{
+	"batch":    "cdap-data-pipeline",
+	"realtime": "cdap-data-streams",
+}
+
+// resourcePipeline deploys a pipeline as an application of the system
+// pipeline artifact, so the artifact reference does not need to be written
+// by hand as with cdap_application.
+// https://docs.cdap.io/cdap/current/en/reference-manual/http-restful-api/lifecycle.html
+func resourcePipeline() *schema.Resource {
+	return &schema.Resource{
+		Create: resourcePipelineCreate,
+		Read:   resourcePipelineRead,
+		Update: resourcePipelineUpdate,
+		Delete: resourcePipelineDelete,
+
+		CustomizeDiff: resourcePipelineCustomizeDiff,
+
+		Schema: map[string]*schema.Schema{
+			"namespace": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Computed:    true,
+				ForceNew:    true,
+				Description: "The name of the namespace in which this resource belongs. If not provided, the provider's default_namespace is used.",
+			},
+			"name": {
+				Type:        schema.TypeString,
+				Required:    true,
+				ForceNew:    true,
+				Description: "The name of the pipeline. This will be used as the unique identifier in the CDAP API.",
+			},
+			"pipeline": {
+				Type:        schema.TypeString,
+				Required:    true,
+				Description: "The pipeline JSON, either as exported from the Studio or only its config. Changing it redeploys the pipeline in place, and changes of the config made outside of Terraform show as drift.",
+				StateFunc: func(v interface{}) string {
+					json, _ := structure.NormalizeJsonString(v)
+					return json
+				},
+			},
+			"type": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				Computed:     true,
+				ForceNew:     true,
+				ValidateFunc: validation.StringInSlice([]string{"batch", "realtime"}, false),
+				Description:  "The type of the pipeline, either batch or realtime. If not set, it is taken from the artifact of the exported pipeline, and defaults to batch.",
+			},
+			"config_vars": configVarsSchema(),
+			"artifact_version": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Computed:    true,
+				Description: "The version of the system pipeline artifact to deploy the pipeline with. If not set, the version of the exported pipeline is used, or else the latest version on the instance. Changing it upgrades the pipeline in place.",
+			},
+		},
+	}
+}
+
+// pipelineSpec is a pipeline JSON split into the artifact and the config. If
+// the JSON is a Studio export, export holds its other top level keys.
+type pipelineSpec struct {
+	artifact *appArtifact
+	config   json.RawMessage
+	export   map[string]json.RawMessage
+}
+
+// parsePipeline parses either a Studio export, which has the config under a
+// config key, or the config alone, which has the stages at the top level.
+func parsePipeline(s string) (*pipelineSpec, error) {
+	var top map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(s), &top); err != nil {
+		return nil, fmt.Errorf("pipeline must be a JSON object: %v", err)
+	}
+	conf, ok := top["config"]
+	if _, hasStages := top["stages"]; !ok || hasStages {
+		return &pipelineSpec{config: json.RawMessage(s)}, nil
+	}
+
+	p := &pipelineSpec{config: conf, export: top}
+	if a, ok := top["artifact"]; ok {
+		p.artifact = new(appArtifact)
+		if err := json.Unmarshal(a, p.artifact); err != nil {
+			return nil, fmt.Errorf("invalid artifact of pipeline: %v", err)
+		}
+	}
+	return p, nil
+}
+
+// pipelineType returns the type of the pipeline deployed from the artifact.
+func pipelineType(artifactName string) (string, bool) {
+	for t, name := range pipelineArtifacts {
+		if name == artifactName {
+			return t, true
+		}
+	}
+	return "", false
+}
+
+// pipelineArtifact resolves the system artifact to deploy the pipeline with.
+func pipelineArtifact(d *schema.ResourceData, config *Config, p *pipelineSpec) (*appArtifact, error) {
+	typ := d.Get("type").(string)
+	if p.artifact != nil {
+		t, ok := pipelineType(p.artifact.Name)
+		if !ok {
+			return nil, fmt.Errorf("artifact %q of the exported pipeline is not a pipeline artifact, want one of %q or %q", p.artifact.Name, pipelineArtifacts["batch"], pipelineArtifacts["realtime"])
+		}
+		if typ != "" && typ != t {
+			return nil, fmt.Errorf("the exported pipeline is a %s pipeline, but type is %s", t, typ)
+		}
+		typ = t
+	}
+	if typ == "" {
+		typ = "batch"
+	}
+
+	a := &appArtifact{Name: pipelineArtifacts[typ], Scope: "SYSTEM"}
+	// A version in the config wins over the one of the export, which wins
+	// over the one last deployed, so upgrading the export upgrades the pipeline.
+	configured := false
+	if c := d.GetRawConfig(); !c.IsNull() && c.IsKnown() && !c.GetAttr("artifact_version").IsNull() {
+		configured = true
+	}
+	if v, ok := d.GetOk("artifact_version"); ok && configured {
+		a.Version = v.(string)
+	} else if p.artifact != nil && p.artifact.Version != "" {
+		a.Version = p.artifact.Version
+	} else if ok {
+		a.Version = v.(string)
+	} else {
+		summaries, err := listArtifactVersions(config, d.Get("namespace").(string), a.Name, "system")
+		if err != nil {
+			return nil, fmt.Errorf("failed to list versions of artifact %q: %v", a.Name, err)
+		}
+		for _, s := range summaries {
+			if compareVersions(s.Version, a.Version) > 0 {
+				a.Version = s.Version
+			}
+		}
+		if a.Version == "" {
+			return nil, fmt.Errorf("artifact %q not found in the system scope", a.Name)
+		}
+	}
+	d.Set("type", typ)
+	d.Set("artifact_version", a.Version)
+	return a, nil
+}
+
+// resourcePipelineCustomizeDiff checks that the pipeline is valid JSON once
+// config_vars are substituted.
+func resourcePipelineCustomizeDiff(ctx context.Context, d *schema.ResourceDiff, m interface{}) error {
+	if !d.NewValueKnown("config_vars") || !d.NewValueKnown("pipeline") {
+		return nil
+	}
+	return validateConfigJSON("pipeline", d.Get("pipeline").(string), d.Get("config_vars").(map[string]interface{}))
+}
+
+func resourcePipelineCreate(d *schema.ResourceData, m interface{}) error {
+	config := m.(*Config)
+	if err := setDefaultNamespace(d, config); err != nil {
+		return err
+	}
+	if err := deployPipeline(d, config); err != nil {
+		return err
+	}
+	d.SetId(d.Get("name").(string))
+	return nil
+}
+
+func resourcePipelineUpdate(d *schema.ResourceData, m interface{}) error {
+	// Deploying on top of an existing pipeline updates it in place.
+	return deployPipeline(d, m.(*Config))
+}
+
+func deployPipeline(d *schema.ResourceData, config *Config) error {
+	s, err := substituteConfigVars(d.Get("pipeline").(string), configVars(d))
+	if err != nil {
+		return fmt.Errorf("pipeline: %v", err)
+	}
+	p, err := parsePipeline(s)
+	if err != nil {
+		return err
+	}
+	a, err := pipelineArtifact(d, config, p)
+	if err != nil {
+		return err
+	}
+	b, err := json.Marshal(&appRequest{Artifact: a, Config: p.config})
+	if err != nil {
+		return err
+	}
+
+	name := d.Get("name").(string)
+	addr := urlJoin(config.host, "/v3/namespaces", d.Get("namespace").(string), "/apps", name)
+	req, err := http.NewRequest(http.MethodPut, addr, bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	if _, err := httpCall(config.httpClient, req); err != nil {
+		return fmt.Errorf("failed to deploy pipeline %q: %v", name, err)
+	}
+	return nil
+}
+
+func resourcePipelineRead(d *schema.ResourceData, m interface{}) error {
+	config := m.(*Config)
+	name := d.Get("name").(string)
+	detail := new(appDetail)
+	err := getJSON(config, urlJoin(config.host, "/v3/namespaces", d.Get("namespace").(string), "/apps", name), detail)
+	if isNotFound(err) {
+		log.Printf("pipeline %q not found, removing from state", name)
+		d.SetId("")
+		return nil
+	}
+	if err != nil {
+		return permissionError(err, fmt.Sprintf("read pipeline %q", name))
+	}
+
+	if detail.Artifact != nil {
+		typ, ok := pipelineType(detail.Artifact.Name)
+		if !ok {
+			return fmt.Errorf("application %q is not a pipeline, it was deployed from artifact %q", name, detail.Artifact.Name)
+		}
+		d.Set("type", typ)
+		d.Set("artifact_version", detail.Artifact.Version)
+	}
+	if detail.Configuration == "" {
+		return nil
+	}
+
+	deployed, err := structure.NormalizeJsonString(detail.Configuration)
+	if err != nil {
+		return fmt.Errorf("failed to parse configuration of pipeline %q: %v", name, err)
+	}
+	// The pipeline in state has the placeholders, so it is compared after
+	// substitution.
+	s, err := substituteConfigVars(d.Get("pipeline").(string), configVars(d))
+	if err != nil {
+		return fmt.Errorf("pipeline: %v", err)
+	}
+	p, err := parsePipeline(s)
+	if err != nil {
+		return err
+	}
+	if conf, err := structure.NormalizeJsonString(string(p.config)); err == nil && conf == deployed {
+		return nil
+	}
+
+	// Keep the shape of the pipeline in state, so only the config shows as drift.
+	if p.export == nil {
+		d.Set("pipeline", escapeConfigVars(deployed, configVars(d)))
+		return nil
+	}
+	p.export["config"] = json.RawMessage(deployed)
+	b, err := json.Marshal(p.export)
+	if err != nil {
+		return err
+	}
+	pipeline, err := structure.NormalizeJsonString(string(b))
+	if err != nil {
+		return err
+	}
+	d.Set("pipeline", escapeConfigVars(pipeline, configVars(d)))
+	return nil
+}
+
+func resourcePipelineDelete(d *schema.ResourceData, m interface{}) error {
+	config := m.(*Config)
+	name := d.Get("name").(string)
+	addr := urlJoin(config.host, "/v3/namespaces", d.Get("namespace").(string), "/apps", name)
+	req, err := http.NewRequest(http.MethodDelete, addr, nil)
+	if err != nil {
+		return err
+	}
+	if _, err := httpCall(config.httpClient, req); err != nil && !isNotFound(err) {
+		return fmt.Errorf("failed to delete pipeline %q: %v", name, err)
+	}
+	return nil
+}
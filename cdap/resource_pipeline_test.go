@@ -0,0 +1,105 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cdap
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/structure"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
+)
+
+// A deployed config that drifted is written back to state escaped, so it
+// substitutes to the deployed config again, including its CDAP macros.
+func TestPipelineReadDriftEscapesPlaceholders(t *testing.T) {
+	const deployed = `{"stages":[],"env":"prod","path":"${macro}","added":"1"}`
+	tests := []struct {
+		name       string
+		pipeline   string
+		configVars map[string]string
+		want       string
+	}{
+		{
+			name:       "config with config_vars",
+			pipeline:   `{"stages":[],"env":"${env}","path":"$${macro}"}`,
+			configVars: map[string]string{"env": "prod"},
+			want:       `{"added":"1","env":"prod","path":"$${macro}","stages":[]}`,
+		},
+		{
+			name:       "export with config_vars",
+			pipeline:   `{"name":"example","artifact":{"name":"cdap-data-pipeline","version":"6.0.0","scope":"SYSTEM"},"config":{"stages":[],"env":"${env}","path":"$${macro}"}}`,
+			configVars: map[string]string{"env": "prod"},
+			want:       `{"artifact":{"name":"cdap-data-pipeline","scope":"SYSTEM","version":"6.0.0"},"config":{"added":"1","env":"prod","path":"$${macro}","stages":[]},"name":"example"}`,
+		},
+		{
+			name:     "config without config_vars",
+			pipeline: `{"stages":[],"env":"prod","path":"${macro}"}`,
+			want:     `{"added":"1","env":"prod","path":"${macro}","stages":[]}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := newTestConfig(t, func(w http.ResponseWriter, r *http.Request) {
+				if r.Method != http.MethodGet || r.URL.Path != "/v3/namespaces/default/apps/example" {
+					t.Errorf("unexpected request %s %s", r.Method, r.URL)
+				}
+				json.NewEncoder(w).Encode(&appDetail{
+					Name:          "example",
+					Configuration: deployed,
+					Artifact:      &appArtifact{Name: "cdap-data-pipeline", Version: "6.0.0", Scope: "SYSTEM"},
+				})
+			})
+			attrs := map[string]string{
+				"name":      "example",
+				"namespace": "default",
+				"pipeline":  tt.pipeline,
+			}
+			if len(tt.configVars) > 0 {
+				attrs["config_vars.%"] = strconv.Itoa(len(tt.configVars))
+			}
+			for k, v := range tt.configVars {
+				attrs["config_vars."+k] = v
+			}
+			d := resourcePipeline().Data(&terraform.InstanceState{ID: "example", Attributes: attrs})
+			if err := resourcePipelineRead(d, config); err != nil {
+				t.Fatalf("Read returned error: %v", err)
+			}
+			got := d.Get("pipeline").(string)
+			if got != tt.want {
+				t.Errorf("pipeline after Read = %s, want %s", got, tt.want)
+			}
+
+			// The next Read finds no more drift.
+			s, err := substituteConfigVars(got, configVars(d))
+			if err != nil {
+				t.Fatalf("substituting the pipeline after Read returned error: %v", err)
+			}
+			p, err := parsePipeline(s)
+			if err != nil {
+				t.Fatal(err)
+			}
+			conf, err := structure.NormalizeJsonString(string(p.config))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want, _ := structure.NormalizeJsonString(deployed); conf != want {
+				t.Errorf("pipeline after Read substitutes to %s, want the deployed %s", conf, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,287 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cdap
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+)
+
+// https://docs.cdap.io/cdap/current/en/reference-manual/http-restful-api/preferences.html
+func resourcePreferences() *schema.Resource {
+	return &schema.Resource{
+		Create: resourcePreferencesCreate,
+		Read:   resourcePreferencesRead,
+		Update: resourcePreferencesUpdate,
+		Delete: resourcePreferencesDelete,
+		Exists: resourcePreferencesExists,
+
+		Schema: map[string]*schema.Schema{
+			"scope": {
+				Type:         schema.TypeString,
+				Required:     true,
+				ForceNew:     true,
+				ValidateFunc: validation.StringInSlice([]string{"instance", "namespace", "application", "program"}, false),
+				Description:  "The level the preferences are set on, one of instance, namespace, application or program.",
+			},
+			"namespace": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Computed:    true,
+				ForceNew:    true,
+				Description: "The name of the namespace the preferences are set on. Ignored for the instance scope. If not provided, the provider's default_namespace is used.",
+			},
+			"application": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				ForceNew:    true,
+				Description: "The name of the application. Required for the application and program scopes.",
+			},
+			"program_type": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				ForceNew:     true,
+				ValidateFunc: validation.StringInSlice([]string{"workflows", "services", "spark", "mapreduce", "workers"}, false),
+				Description:  "The type of the program, one of workflows, services, spark, mapreduce or workers. Required for the program scope.",
+			},
+			"program_name": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				ForceNew:    true,
+				Description: "The name of the program. Required for the program scope.",
+			},
+			"preferences": {
+				Type:        schema.TypeMap,
+				Required:    true,
+				Description: "The preferences to set.",
+				Elem: &schema.Schema{
+					Type: schema.TypeString,
+				},
+			},
+			"merge": {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				ForceNew:    true,
+				Default:     false,
+				Description: "Whether to merge the preferences into the ones already set instead of replacing them. If set, only the preferences managed by this resource are refreshed and removed on delete.",
+			},
+		},
+	}
+}
+
+// preferencesID returns the ID of the preferences along with the path of
+// their API relative to /v3.
+func preferencesID(d *schema.ResourceData) (string, string, error) {
+	scope := d.Get("scope").(string)
+	if scope == "instance" {
+		return "instance", "preferences", nil
+	}
+
+	parts := []string{d.Get("namespace").(string)}
+	paths := []string{"namespaces", parts[0]}
+	if scope == "application" || scope == "program" {
+		app := d.Get("application").(string)
+		if app == "" {
+			return "", "", fmt.Errorf("application must be set for the %v scope", scope)
+		}
+		parts = append(parts, app)
+		paths = append(paths, "apps", app)
+	}
+	if scope == "program" {
+		pType, pName := d.Get("program_type").(string), d.Get("program_name").(string)
+		if pType == "" || pName == "" {
+			return "", "", fmt.Errorf("program_type and program_name must be set for the %v scope", scope)
+		}
+		parts = append(parts, pType, pName)
+		paths = append(paths, pType, pName)
+	}
+	return strings.Join(parts, "/"), strings.Join(append(paths, "preferences"), "/"), nil
+}
+
+func resourcePreferencesCreate(d *schema.ResourceData, m interface{}) error {
+	config := m.(*Config)
+	if d.Get("scope").(string) != "instance" {
+		if err := setDefaultNamespace(d, config); err != nil {
+			return err
+		}
+	}
+	id, _, err := preferencesID(d)
+	if err != nil {
+		return err
+	}
+	if err := putPreferences(d, config, nil); err != nil {
+		return err
+	}
+
+	d.SetId(id)
+	return nil
+}
+
+func resourcePreferencesUpdate(d *schema.ResourceData, m interface{}) error {
+	config := m.(*Config)
+	old, _ := d.GetChange("preferences")
+
+	var removed []string
+	for k := range old.(map[string]interface{}) {
+		if _, ok := d.Get("preferences").(map[string]interface{})[k]; !ok {
+			removed = append(removed, k)
+		}
+	}
+	return putPreferences(d, config, removed)
+}
+
+// putPreferences sets the preferences of the resource. If merge is set, the
+// preferences are merged into the current ones after dropping the removed keys.
+func putPreferences(d *schema.ResourceData, config *Config, removed []string) error {
+	_, path, err := preferencesID(d)
+	if err != nil {
+		return err
+	}
+	addr := urlJoin(config.host, "/v3", path)
+
+	prefs := make(map[string]string)
+	if d.Get("merge").(bool) {
+		if prefs, err = getPreferences(config, addr); err != nil {
+			return err
+		}
+		for _, k := range removed {
+			delete(prefs, k)
+		}
+	}
+	for k, v := range d.Get("preferences").(map[string]interface{}) {
+		prefs[k] = v.(string)
+	}
+	return replacePreferences(config, addr, prefs)
+}
+
+// replacePreferences replaces all preferences at the address with the given
+// ones. If there are none, the preferences are deleted.
+func replacePreferences(config *Config, addr string, prefs map[string]string) error {
+	method := http.MethodPut
+	var body []byte
+	if len(prefs) == 0 {
+		method = http.MethodDelete
+	} else {
+		b, err := json.Marshal(prefs)
+		if err != nil {
+			return err
+		}
+		body = b
+	}
+
+	req, err := http.NewRequest(method, addr, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	_, err = httpCall(config.httpClient, req)
+	return err
+}
+
+func getPreferences(config *Config, addr string) (map[string]string, error) {
+	req, err := http.NewRequest(http.MethodGet, addr, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	b, err := httpCall(config.httpClient, req)
+	if err != nil {
+		return nil, err
+	}
+
+	prefs := make(map[string]string)
+	if err := json.Unmarshal(b, &prefs); err != nil {
+		return nil, err
+	}
+	return prefs, nil
+}
+
+func resourcePreferencesRead(d *schema.ResourceData, m interface{}) error {
+	config := m.(*Config)
+	_, path, err := preferencesID(d)
+	if err != nil {
+		return err
+	}
+
+	prefs, err := getPreferences(config, urlJoin(config.host, "/v3", path))
+	if isNotFound(err) {
+		log.Printf("preferences %q not found, removing from state", d.Id())
+		d.SetId("")
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if d.Get("merge").(bool) {
+		managed := make(map[string]string)
+		for k := range d.Get("preferences").(map[string]interface{}) {
+			if v, ok := prefs[k]; ok {
+				managed[k] = v
+			}
+		}
+		prefs = managed
+	}
+	d.Set("preferences", prefs)
+	return nil
+}
+
+func resourcePreferencesDelete(d *schema.ResourceData, m interface{}) error {
+	config := m.(*Config)
+	_, path, err := preferencesID(d)
+	if err != nil {
+		return err
+	}
+	addr := urlJoin(config.host, "/v3", path)
+
+	if d.Get("merge").(bool) {
+		// Only remove the preferences managed by this resource.
+		prefs, err := getPreferences(config, addr)
+		if err != nil {
+			return err
+		}
+		for k := range d.Get("preferences").(map[string]interface{}) {
+			delete(prefs, k)
+		}
+		return replacePreferences(config, addr, prefs)
+	}
+
+	req, err := http.NewRequest(http.MethodDelete, addr, nil)
+	if err != nil {
+		return err
+	}
+	_, err = httpCall(config.httpClient, req)
+	return err
+}
+
+func resourcePreferencesExists(d *schema.ResourceData, m interface{}) (bool, error) {
+	config := m.(*Config)
+	_, path, err := preferencesID(d)
+	if err != nil {
+		return false, err
+	}
+
+	_, err = getPreferences(config, urlJoin(config.host, "/v3", path))
+	if isNotFound(err) {
+		return false, nil
+	}
+	return err == nil, err
+}
@@ -18,11 +18,15 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
+	"log"
 	"net/http"
+	"strings"
 
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
 )
 
+// resourceProfile manages compute profiles, which define where programs run.
 // https://docs.cdap.io/cdap/current/en/reference-manual/http-restful-api/profile.html
 func resourceProfile() *schema.Resource {
 	return &schema.Resource{
@@ -41,11 +45,22 @@ func resourceProfile() *schema.Resource {
 			"namespace": {
 				Type:        schema.TypeString,
 				Optional:    true,
+				Computed:    true,
 				ForceNew:    true,
-				Description: "The name of the namespace in which this resource belongs. If not provided, the default namespace is used.",
-				DefaultFunc: func() (interface{}, error) {
-					return defaultNamespace, nil
-				},
+				Description: "The name of the namespace in which this resource belongs. Ignored for system profiles. If not provided, the provider's default_namespace is used.",
+			},
+			"scope": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				ForceNew:     true,
+				Default:      "user",
+				ValidateFunc: validation.StringInSlice([]string{"user", "system"}, false),
+				Description:  "The scope of the profile, either user or system. System profiles are available in all namespaces.",
+			},
+			"status": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "The status of the profile as reported by CDAP, either ENABLED or DISABLED.",
 			},
 			"label": {
 				Type:        schema.TypeString,
@@ -88,9 +103,16 @@ func resourceProfile() *schema.Resource {
 									},
 									"value": {
 										Type:        schema.TypeString,
-										Required:    true,
+										Optional:    true,
 										ForceNew:    true,
-										Description: "The value of the property.",
+										Description: "The value of the property. Exactly one of value and sensitive_value must be set.",
+									},
+									"sensitive_value": {
+										Type:        schema.TypeString,
+										Optional:    true,
+										ForceNew:    true,
+										Sensitive:   true,
+										Description: "The value of the property, for values such as service account keys that should be redacted from plans.",
 									},
 									"is_editable": {
 										Type:        schema.TypeBool,
@@ -115,6 +137,8 @@ type profile struct {
 	Label       string       `json:"label"`
 	Description string       `json:"description,omitempty"`
 	Provisioner *provisioner `json:"provisioner"`
+	Scope       string       `json:"scope,omitempty"`
+	Status      string       `json:"status,omitempty"`
 }
 
 type provisioner struct {
@@ -128,8 +152,19 @@ type property struct {
 	IsEditable bool   `json:"isEditable"`
 }
 
+// profilesAddr returns the address of the profiles in the scope of the resource.
+func profilesAddr(config *Config, d *schema.ResourceData) string {
+	if d.Get("scope").(string) == "system" {
+		return urlJoin(config.host, "/v3/profiles")
+	}
+	return urlJoin(config.host, "/v3/namespaces", d.Get("namespace").(string), "/profiles")
+}
+
 func resourceProfileCreate(d *schema.ResourceData, m interface{}) error {
 	config := m.(*Config)
+	if err := setDefaultNamespace(d, config); err != nil {
+		return err
+	}
 	name := d.Get("name").(string)
 
 	prof := &profile{
@@ -143,15 +178,19 @@ func resourceProfileCreate(d *schema.ResourceData, m interface{}) error {
 	}
 	for _, rawProp := range rawProv["properties"].([]interface{}) {
 		rawPropMap := rawProp.(map[string]interface{})
+		value, sensitiveValue := rawPropMap["value"].(string), rawPropMap["sensitive_value"].(string)
+		if (value == "") == (sensitiveValue == "") {
+			return fmt.Errorf("property %q of profile %q must set exactly one of value and sensitive_value", rawPropMap["name"], name)
+		}
 		prov.Properties = append(prov.Properties, &property{
 			Name:       rawPropMap["name"].(string),
-			Value:      rawPropMap["value"].(string),
+			Value:      value + sensitiveValue,
 			IsEditable: rawPropMap["is_editable"].(bool),
 		})
 	}
 	prof.Provisioner = prov
 
-	addr := urlJoin(config.host, "/v3/namespaces", d.Get("namespace").(string), "/profiles", name)
+	addr := urlJoin(profilesAddr(config, d), name)
 
 	b, err := json.Marshal(prof)
 	if err != nil {
@@ -166,10 +205,73 @@ func resourceProfileCreate(d *schema.ResourceData, m interface{}) error {
 	}
 
 	d.SetId(name)
-	return nil
+	return resourceProfileRead(d, m)
 }
 
 func resourceProfileRead(d *schema.ResourceData, m interface{}) error {
+	config := m.(*Config)
+	name := d.Get("name").(string)
+
+	req, err := http.NewRequest(http.MethodGet, urlJoin(profilesAddr(config, d), name), nil)
+	if err != nil {
+		return err
+	}
+	b, err := httpCall(config.httpClient, req)
+	if isNotFound(err) {
+		log.Printf("profile %q not found, removing from state", name)
+		d.SetId("")
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	prof := new(profile)
+	if err := json.Unmarshal(b, prof); err != nil {
+		return err
+	}
+
+	if prof.Provisioner != nil {
+		values := make(map[string]*property)
+		for _, p := range prof.Provisioner.Properties {
+			values[p.Name] = p
+		}
+
+		// Only report the configured properties, in their configured order, so
+		// defaults added by CDAP do not show as drift. Sensitive values are kept
+		// in the attribute they were configured with.
+		var props []map[string]interface{}
+		if rawProvs := d.Get("profile_provisioner").([]interface{}); len(rawProvs) > 0 {
+			for _, rawProp := range rawProvs[0].(map[string]interface{})["properties"].([]interface{}) {
+				rawPropMap := rawProp.(map[string]interface{})
+				p, ok := values[rawPropMap["name"].(string)]
+				if !ok {
+					continue
+				}
+				prop := map[string]interface{}{
+					"name":        p.Name,
+					"is_editable": p.IsEditable,
+				}
+				if rawPropMap["sensitive_value"].(string) != "" {
+					prop["sensitive_value"] = p.Value
+				} else {
+					prop["value"] = p.Value
+				}
+				props = append(props, prop)
+			}
+		}
+		d.Set("profile_provisioner", []map[string]interface{}{{
+			"name":       prof.Provisioner.Name,
+			"properties": props,
+		}})
+	}
+
+	if prof.Scope != "" {
+		d.Set("scope", strings.ToLower(prof.Scope))
+	}
+	d.Set("label", prof.Label)
+	d.Set("description", prof.Description)
+	d.Set("status", prof.Status)
 	return nil
 }
 
@@ -177,9 +279,9 @@ func resourceProfileDelete(d *schema.ResourceData, m interface{}) error {
 	config := m.(*Config)
 	name := d.Get("name").(string)
 
-	addr := urlJoin(config.host, "/v3/namespaces", d.Get("namespace").(string), "/profiles", name)
+	addr := urlJoin(profilesAddr(config, d), name)
 
-	// Disable the profile first.
+	// CDAP only deletes disabled profiles.
 	req, err := http.NewRequest(http.MethodPost, urlJoin(addr, "/disable"), nil)
 	if err != nil {
 		return err
@@ -200,16 +302,16 @@ func resourceProfileExists(d *schema.ResourceData, m interface{}) (bool, error)
 	config := m.(*Config)
 	name := d.Get("name").(string)
 
-	namespace := d.Get("namespace").(string)
-	if exists, err := namespaceExists(config, namespace); err != nil {
-		return false, fmt.Errorf("failed to check for existence of namespace %q: %v", namespace, err)
-	} else if !exists {
-		return false, nil
+	if d.Get("scope").(string) != "system" {
+		namespace := d.Get("namespace").(string)
+		if exists, err := namespaceExists(config, namespace); err != nil {
+			return false, fmt.Errorf("failed to check for existence of namespace %q: %v", namespace, err)
+		} else if !exists {
+			return false, nil
+		}
 	}
 
-	addr := urlJoin(config.host, "/v3/namespaces", namespace, "/profiles")
-
-	req, err := http.NewRequest(http.MethodGet, addr, nil)
+	req, err := http.NewRequest(http.MethodGet, profilesAddr(config, d), nil)
 	if err != nil {
 		return false, err
 	}
@@ -0,0 +1,165 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cdap
+
+import (
+	"log"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+)
+
+// profilePreference is the preference CDAP uses to pick the compute profile
+// programs run with.
+const profilePreference = "system.profile.name"
+
+// resourceProfileAssignment sets the compute profile programs run with at the
+// chosen level, leaving other preferences untouched.
+// https://docs.cdap.io/cdap/current/en/reference-manual/http-restful-api/preferences.html
+func resourceProfileAssignment() *schema.Resource {
+	return &schema.Resource{
+		Create: resourceProfileAssignmentPut,
+		Read:   resourceProfileAssignmentRead,
+		Update: resourceProfileAssignmentPut,
+		Delete: resourceProfileAssignmentDelete,
+
+		Schema: map[string]*schema.Schema{
+			"scope": {
+				Type:         schema.TypeString,
+				Required:     true,
+				ForceNew:     true,
+				ValidateFunc: validation.StringInSlice([]string{"instance", "namespace", "application", "program"}, false),
+				Description:  "The level the profile is assigned at, one of instance, namespace, application or program.",
+			},
+			"namespace": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Computed:    true,
+				ForceNew:    true,
+				Description: "The name of the namespace the profile is assigned in. Ignored for the instance scope. If not provided, the provider's default_namespace is used.",
+			},
+			"application": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				ForceNew:    true,
+				Description: "The name of the application. Required for the application and program scopes.",
+			},
+			"program_type": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				ForceNew:     true,
+				ValidateFunc: validation.StringInSlice([]string{"workflows", "services", "spark", "mapreduce", "workers"}, false),
+				Description:  "The type of the program, one of workflows, services, spark, mapreduce or workers. Required for the program scope.",
+			},
+			"program_name": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				ForceNew:    true,
+				Description: "The name of the program. Required for the program scope.",
+			},
+			"profile": {
+				Type:        schema.TypeString,
+				Required:    true,
+				Description: "The name of the compute profile to assign.",
+			},
+			"profile_scope": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				Default:      "user",
+				ValidateFunc: validation.StringInSlice([]string{"user", "system"}, false),
+				Description:  "The scope of the compute profile, either user or system.",
+			},
+		},
+	}
+}
+
+func resourceProfileAssignmentPut(d *schema.ResourceData, m interface{}) error {
+	config := m.(*Config)
+	if d.Get("scope").(string) != "instance" {
+		if err := setDefaultNamespace(d, config); err != nil {
+			return err
+		}
+	}
+	id, path, err := preferencesID(d)
+	if err != nil {
+		return err
+	}
+	addr := urlJoin(config.host, "/v3", path)
+
+	prefs, err := getPreferences(config, addr)
+	if err != nil {
+		return err
+	}
+	prefs[profilePreference] = strings.ToUpper(d.Get("profile_scope").(string)) + ":" + d.Get("profile").(string)
+	if err := replacePreferences(config, addr, prefs); err != nil {
+		return err
+	}
+
+	d.SetId(id)
+	return nil
+}
+
+func resourceProfileAssignmentRead(d *schema.ResourceData, m interface{}) error {
+	config := m.(*Config)
+	_, path, err := preferencesID(d)
+	if err != nil {
+		return err
+	}
+
+	prefs, err := getPreferences(config, urlJoin(config.host, "/v3", path))
+	if isNotFound(err) {
+		log.Printf("preferences %q not found, removing from state", d.Id())
+		d.SetId("")
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	v, ok := prefs[profilePreference]
+	if !ok {
+		log.Printf("no profile assigned at %q, removing from state", d.Id())
+		d.SetId("")
+		return nil
+	}
+	// Profiles without a scope prefix are user profiles.
+	scope, profile := "user", v
+	if parts := strings.SplitN(v, ":", 2); len(parts) == 2 {
+		scope, profile = strings.ToLower(parts[0]), parts[1]
+	}
+	d.Set("profile", profile)
+	d.Set("profile_scope", scope)
+	return nil
+}
+
+func resourceProfileAssignmentDelete(d *schema.ResourceData, m interface{}) error {
+	config := m.(*Config)
+	_, path, err := preferencesID(d)
+	if err != nil {
+		return err
+	}
+	addr := urlJoin(config.host, "/v3", path)
+
+	prefs, err := getPreferences(config, addr)
+	if isNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	delete(prefs, profilePreference)
+	return replacePreferences(config, addr, prefs)
+}
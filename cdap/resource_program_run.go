@@ -0,0 +1,264 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cdap
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+)
+
+// resourceProgramRun keeps a long running program such as a service or a
+// worker running. Unlike cdap_streaming_program_run it does not track a
+// single run, so a program that was stopped is started again on the next apply.
+// https://docs.cdap.io/cdap/current/en/reference-manual/http-restful-api/lifecycle.html.
+func resourceProgramRun() *schema.Resource {
+	return &schema.Resource{
+		Create: resourceProgramRunCreate,
+		Read:   resourceProgramRunRead,
+		Delete: resourceProgramRunDelete,
+		Exists: resourceProgramRunExists,
+
+		Schema: map[string]*schema.Schema{
+			"namespace": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Computed:    true,
+				ForceNew:    true,
+				Description: "The name of the namespace in which this resource belongs. If not provided, the provider's default_namespace is used.",
+			},
+			"app": {
+				Type:        schema.TypeString,
+				Required:    true,
+				ForceNew:    true,
+				Description: "Name of the application.",
+			},
+			"type": {
+				Type:         schema.TypeString,
+				Required:     true,
+				ForceNew:     true,
+				Description:  "Either services or workers. Batch programs such as workflows finish on their own, after which they would be started again on every apply, so they are not supported.",
+				ValidateFunc: validation.StringInSlice([]string{"services", "workers"}, false),
+			},
+			"program": {
+				Type:        schema.TypeString,
+				Required:    true,
+				ForceNew:    true,
+				Description: "Name of the program.",
+			},
+			"runtime_args": {
+				Type:        schema.TypeMap,
+				Optional:    true,
+				ForceNew:    true,
+				Description: "The runtime arguments used to start the program.",
+				Elem:        &schema.Schema{Type: schema.TypeString},
+			},
+			"wait_for_status": {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				ForceNew:    true,
+				Default:     true,
+				Description: "Whether to wait for the program to be RUNNING after starting it and STOPPED after stopping it.",
+			},
+			"status": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "The status of the program as reported by CDAP.",
+			},
+			"effective_runtime_args": {
+				Type:        schema.TypeMap,
+				Computed:    true,
+				Description: "The runtime arguments the latest run of the program started with, as recorded by CDAP. Unlike runtime_args, these include the values injected from preferences, which helps finding out why a run behaved unexpectedly. Empty if the program has no runs yet.",
+				Elem:        &schema.Schema{Type: schema.TypeString},
+			},
+		},
+		Timeouts: &schema.ResourceTimeout{
+			Create: schema.DefaultTimeout(10 * time.Minute),
+			Delete: schema.DefaultTimeout(10 * time.Minute),
+		},
+	}
+}
+
+func resourceProgramRunCreate(d *schema.ResourceData, m interface{}) error {
+	config := m.(*Config)
+	if err := setDefaultNamespace(d, config); err != nil {
+		return err
+	}
+	addr := getProgramAddr(config, d)
+
+	args := make(map[string]string)
+	for k, v := range d.Get("runtime_args").(map[string]interface{}) {
+		args[k] = v.(string)
+	}
+	b, err := json.Marshal(args)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, urlJoin(addr, "/start"), bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	if _, err := httpCall(config.httpClient, req); err != nil {
+		return err
+	}
+
+	d.SetId(strings.Join([]string{d.Get("namespace").(string), d.Get("app").(string), d.Get("type").(string), d.Get("program").(string)}, "/"))
+	if d.Get("wait_for_status").(bool) {
+		if err := waitForProgramStatus(config, addr, "RUNNING", d.Timeout(schema.TimeoutCreate)); err != nil {
+			return err
+		}
+	}
+
+	// Not using Read since the program may not have left the STOPPED status yet.
+	status, err := getProgramStatus(config, addr)
+	if err != nil {
+		return err
+	}
+	d.Set("status", status)
+	return setEffectiveRuntimeArgs(d, config, addr)
+}
+
+// setEffectiveRuntimeArgs sets effective_runtime_args from the latest run of
+// the program.
+func setEffectiveRuntimeArgs(d *schema.ResourceData, config *Config, addr string) error {
+	args, err := latestRunArgs(config, addr)
+	if err != nil {
+		return fmt.Errorf("failed to read runtime arguments of program %q: %v", d.Id(), err)
+	}
+	d.Set("effective_runtime_args", args)
+	return nil
+}
+
+// latestRunArgs returns the runtime arguments of the latest run of the
+// program, or an empty map if it has no runs.
+func latestRunArgs(config *Config, addr string) (map[string]string, error) {
+	var runs []struct {
+		Properties struct {
+			RuntimeArgs string `json:"runtimeArgs"`
+		} `json:"properties"`
+	}
+	// CDAP lists the most recent run first.
+	if err := getJSON(config, urlJoin(addr, "/runs?limit=1"), &runs); err != nil {
+		return nil, err
+	}
+	args := make(map[string]string)
+	if len(runs) == 0 || runs[0].Properties.RuntimeArgs == "" {
+		return args, nil
+	}
+	if err := json.Unmarshal([]byte(runs[0].Properties.RuntimeArgs), &args); err != nil {
+		return nil, fmt.Errorf("failed to parse runtime arguments %q: %v", runs[0].Properties.RuntimeArgs, err)
+	}
+	return args, nil
+}
+
+// waitForProgramStatus polls the status of the program until it is the given one.
+func waitForProgramStatus(config *Config, addr, want string, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(config.httpClient.stopCtx, timeout)
+	defer cancel()
+	err := pollUntil(ctx, 5*time.Second, func() (bool, error) {
+		status, err := getProgramStatus(config, addr)
+		return status == want, err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to wait for program to be %v: %v", want, err)
+	}
+	return nil
+}
+
+func getProgramStatus(config *Config, addr string) (string, error) {
+	req, err := http.NewRequest(http.MethodGet, urlJoin(addr, "/status"), nil)
+	if err != nil {
+		return "", err
+	}
+
+	b, err := httpCall(config.httpClient, req)
+	if err != nil {
+		return "", err
+	}
+
+	var p programStatus
+	if err := json.Unmarshal(b, &p); err != nil {
+		return "", err
+	}
+	return p.Status, nil
+}
+
+func resourceProgramRunRead(d *schema.ResourceData, m interface{}) error {
+	config := m.(*Config)
+	status, err := getProgramStatus(config, getProgramAddr(config, d))
+	if isNotFound(err) {
+		log.Printf("program %q not found, removing from state", d.Id())
+		d.SetId("")
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if status == "STOPPED" {
+		log.Printf("program %q is stopped, removing from state so it is started again", d.Id())
+		d.SetId("")
+		return nil
+	}
+	d.Set("status", status)
+	return setEffectiveRuntimeArgs(d, config, getProgramAddr(config, d))
+}
+
+func resourceProgramRunDelete(d *schema.ResourceData, m interface{}) error {
+	config := m.(*Config)
+	addr := getProgramAddr(config, d)
+
+	status, err := getProgramStatus(config, addr)
+	if err != nil {
+		return err
+	}
+	if status == "STOPPED" {
+		return nil
+	}
+
+	if err := stopProgramRun(config, urlJoin(addr, "/stop")); err != nil {
+		return fmt.Errorf("error stopping program: %v", err)
+	}
+	if d.Get("wait_for_status").(bool) {
+		return waitForProgramStatus(config, addr, "STOPPED", d.Timeout(schema.TimeoutDelete))
+	}
+	return nil
+}
+
+func resourceProgramRunExists(d *schema.ResourceData, m interface{}) (bool, error) {
+	config := m.(*Config)
+
+	namespace := d.Get("namespace").(string)
+	if exists, err := namespaceExists(config, namespace); err != nil {
+		return false, fmt.Errorf("failed to check for existence of namespace %q: %v", namespace, err)
+	} else if !exists {
+		return false, nil
+	}
+
+	_, err := getProgramStatus(config, getProgramAddr(config, d))
+	if isNotFound(err) {
+		return false, nil
+	}
+	return err == nil, err
+}
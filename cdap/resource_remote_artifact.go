@@ -0,0 +1,218 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cdap
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+
+	"cloud.google.com/go/storage"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/structure"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/google"
+	"google.golang.org/api/option"
+)
+
+// resourceRemoteArtifact supports deploying an artifact whose JAR is downloaded
+// from an http(s):// or gs:// URL, for teams that publish their plugin JARs to
+// an artifact repository instead of keeping them next to the Terraform config.
+func resourceRemoteArtifact() *schema.Resource {
+	return &schema.Resource{
+		Create: resourceRemoteArtifactCreate,
+		Read:   resourceLocalArtifactRead,
+		Delete: resourceLocalArtifactDelete,
+		Exists: resourceLocalArtifactExists,
+		// The JSON config is local, so it can be validated at plan time.
+		CustomizeDiff: resourceLocalArtifactCustomizeDiff,
+
+		Schema: map[string]*schema.Schema{
+			"name": {
+				Type:        schema.TypeString,
+				Required:    true,
+				ForceNew:    true,
+				Description: "The name of the artifact.",
+			},
+			"namespace": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Computed:    true,
+				ForceNew:    true,
+				Description: "The name of the namespace in which this resource belongs. If not provided, the provider's default_namespace is used.",
+			},
+			// Technically, we could omit the version in the API call because CDAP will infer the
+			// version from the jar. However, forcing the user to specify the version makes dealing
+			// with the resource easier because other API calls require it.
+			"version": {
+				Type:        schema.TypeString,
+				Required:    true,
+				ForceNew:    true,
+				Description: "The version of the artifact. Must match the version in the JAR manifest.",
+			},
+			"jar_url": {
+				Type:        schema.TypeString,
+				Required:    true,
+				ForceNew:    true,
+				Description: "The http(s):// or gs:// URL to download the JAR binary for the artifact from.",
+			},
+			"json_config_path": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				ForceNew:     true,
+				ExactlyOneOf: []string{"json_config", "json_config_path"},
+				Description:  "The local path to the JSON config of the artifact.",
+			},
+			"json_config": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				ForceNew:     true,
+				ExactlyOneOf: []string{"json_config", "json_config_path"},
+				ValidateFunc: validation.StringIsJSON,
+				StateFunc: func(v interface{}) string {
+					json, _ := structure.NormalizeJsonString(v)
+					return json
+				},
+				Description: "The JSON config of the artifact, for small configs that are not worth a file, such as ones generated with jsonencode.",
+			},
+			"sha256": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				ForceNew:    true,
+				Description: "The expected hex encoded SHA-256 checksum of the JAR. If set, the downloaded JAR is verified against it before upload.",
+			},
+			"scope": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "The scope of the artifact as reported by CDAP, either user or system.",
+			},
+			"plugin_classes": pluginClassesSchema("The plugin classes the uploaded artifact registers, as reported by CDAP."),
+		},
+	}
+}
+
+func resourceRemoteArtifactCreate(d *schema.ResourceData, m interface{}) error {
+	config := m.(*Config)
+	ctx := config.httpClient.stopCtx
+	if err := setDefaultNamespace(d, config); err != nil {
+		return err
+	}
+
+	a, err := loadRemoteArtifact(ctx, d, config)
+	if err != nil {
+		return err
+	}
+	return uploadArtifact(config, d, a)
+}
+
+func loadRemoteArtifact(ctx context.Context, d *schema.ResourceData, config *Config) (*artifact, error) {
+	jarURL := d.Get("jar_url").(string)
+	jar, err := downloadJar(ctx, config, jarURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download JAR from %q: %v", jarURL, err)
+	}
+	if err := checkJarSize(config, int64(len(jar)), jarURL); err != nil {
+		return nil, err
+	}
+
+	if want, ok := d.GetOk("sha256"); ok {
+		sum := sha256.Sum256(jar)
+		if got := hex.EncodeToString(sum[:]); !strings.EqualFold(got, want.(string)) {
+			return nil, fmt.Errorf("checksum mismatch for JAR downloaded from %q: got sha256 %v, want %v", jarURL, got, want)
+		}
+	}
+	if err := validateArtifactVersion(bytes.NewReader(jar), int64(len(jar)), d.Get("version").(string)); err != nil {
+		return nil, err
+	}
+
+	conf, err := localArtifactConfig(d, config)
+	if err != nil {
+		return nil, err
+	}
+
+	return &artifact{
+		name:    d.Get("name").(string),
+		version: d.Get("version").(string),
+		config:  conf,
+		jar:     jarFromBytes(jar),
+	}, nil
+}
+
+func downloadJar(ctx context.Context, config *Config, jarURL string) ([]byte, error) {
+	switch {
+	case strings.HasPrefix(jarURL, "gs://"):
+		opts, authenticated, err := gcsClientOptions(ctx, config)
+		if err != nil {
+			return nil, err
+		}
+		storageClient, err := storage.NewClient(ctx, append(opts, option.WithScopes(storage.ScopeReadOnly))...)
+		if err != nil {
+			return nil, err
+		}
+		defer storageClient.Close()
+		b, err := readObject(ctx, storageClient, jarURL)
+		if err != nil && !authenticated {
+			return nil, fmt.Errorf("%v (no Google credentials were found, set credentials_file or configure Application Default Credentials to read private buckets)", err)
+		}
+		return b, err
+	case strings.HasPrefix(jarURL, "http://"), strings.HasPrefix(jarURL, "https://"):
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, jarURL, nil)
+		if err != nil {
+			return nil, err
+		}
+		// Use a plain client so the CDAP token is not sent to the artifact repository.
+		client := &apiClient{
+			Client:       &http.Client{Timeout: config.httpClient.Timeout},
+			maxRetries:   config.httpClient.maxRetries,
+			retryMaxWait: config.httpClient.retryMaxWait,
+			stopCtx:      config.httpClient.stopCtx,
+		}
+		return httpCall(client, req)
+	default:
+		return nil, fmt.Errorf("unsupported URL scheme, want http://, https:// or gs://")
+	}
+}
+
+// gcsClientOptions returns the options to authenticate to GCS with, and
+// whether any credentials were found. The credentials_file takes precedence
+// over Application Default Credentials, which take precedence over the
+// provider's token. Without any, public objects can still be read.
+func gcsClientOptions(ctx context.Context, config *Config) ([]option.ClientOption, bool, error) {
+	if config.credentialsFile != "" {
+		if _, err := os.Stat(config.credentialsFile); err != nil {
+			return nil, false, fmt.Errorf("failed to read credentials_file: %v", err)
+		}
+		return []option.ClientOption{option.WithCredentialsFile(config.credentialsFile)}, true, nil
+	}
+	creds, err := google.FindDefaultCredentials(ctx, storage.ScopeReadOnly)
+	if err == nil {
+		return []option.ClientOption{option.WithCredentials(creds)}, true, nil
+	}
+	log.Printf("[DEBUG] no Application Default Credentials found: %v", err)
+	if config.token != "" {
+		return []option.ClientOption{option.WithTokenSource(oauth2.StaticTokenSource(&oauth2.Token{
+			AccessToken: config.token,
+			TokenType:   "Bearer",
+		}))}, true, nil
+	}
+	return []option.ClientOption{option.WithoutAuthentication()}, false, nil
+}
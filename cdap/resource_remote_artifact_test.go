@@ -0,0 +1,80 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cdap
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
+)
+
+// cdap_remote_artifact shares its CustomizeDiff with cdap_local_artifact, so
+// planning it must not read attributes only cdap_local_artifact has.
+func TestRemoteArtifactDiff(t *testing.T) {
+	conf := terraform.NewResourceConfigRaw(map[string]interface{}{
+		"name":        "example",
+		"version":     "1.0.0",
+		"jar_url":     "https://example.com/example-1.0.0.jar",
+		"json_config": `{"parents": ["system:cdap-data-pipeline[6.0.0,7.0.0)"]}`,
+	})
+	if _, err := resourceRemoteArtifact().Diff(context.Background(), nil, conf, &Config{}); err != nil {
+		t.Fatalf("Diff returned error: %v", err)
+	}
+}
+
+// Exists must only report the version in state, not any other version of the
+// same artifact.
+func TestArtifactExists(t *testing.T) {
+	tests := []struct {
+		name     string
+		versions string
+		code     int
+		want     bool
+	}{
+		{name: "version in state", versions: `[{"name": "example", "version": "1.0.0"}, {"name": "example", "version": "2.0.0"}]`, want: true},
+		{name: "other version only", versions: `[{"name": "example", "version": "2.0.0"}]`, want: false},
+		{name: "no versions", code: http.StatusNotFound, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := newTestConfig(t, func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/v3/namespaces/default/artifacts/example" {
+					t.Errorf("unexpected request %v %v", r.Method, r.URL)
+				}
+				if tt.code != 0 {
+					w.WriteHeader(tt.code)
+				}
+				w.Write([]byte(tt.versions))
+			})
+			d := resourceRemoteArtifact().Data(&terraform.InstanceState{
+				ID: "example",
+				Attributes: map[string]string{
+					"name":      "example",
+					"namespace": "default",
+					"version":   "1.0.0",
+				},
+			})
+			got, err := resourceLocalArtifactExists(d, config)
+			if err != nil {
+				t.Fatalf("resourceLocalArtifactExists returned error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("resourceLocalArtifactExists() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,187 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cdap
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+// resourceRouteConfig splits the traffic of a service across versions of its
+// application, for canary and blue/green rollouts.
+// https://docs.cdap.io/cdap/current/en/reference-manual/http-restful-api/route-config.html
+func resourceRouteConfig() *schema.Resource {
+	return &schema.Resource{
+		Create: resourceRouteConfigPut,
+		Read:   resourceRouteConfigRead,
+		Update: resourceRouteConfigPut,
+		Delete: resourceRouteConfigDelete,
+
+		Schema: map[string]*schema.Schema{
+			"namespace": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Computed:    true,
+				ForceNew:    true,
+				Description: "The name of the namespace in which the application belongs. If not provided, the provider's default_namespace is used.",
+			},
+			"app": {
+				Type:        schema.TypeString,
+				Required:    true,
+				ForceNew:    true,
+				Description: "The name of the application.",
+			},
+			"service": {
+				Type:        schema.TypeString,
+				Required:    true,
+				ForceNew:    true,
+				Description: "The name of the service of the application.",
+			},
+			"routes": {
+				Type:         schema.TypeMap,
+				Required:     true,
+				ValidateFunc: validateRoutes,
+				Description:  "The percentage of requests to route to each application version. The percentages must add up to 100, and the versions must be deployed.",
+				Elem: &schema.Schema{
+					Type: schema.TypeInt,
+				},
+			},
+		},
+	}
+}
+
+func validateRoutes(v interface{}, k string) ([]string, []error) {
+	total := 0
+	for version, w := range v.(map[string]interface{}) {
+		// Config values may still be strings at validation time.
+		weight, err := strconv.Atoi(fmt.Sprint(w))
+		if err != nil {
+			return nil, []error{fmt.Errorf("%s: percentage of version %q must be a whole number, got %v", k, version, w)}
+		}
+		if weight < 0 {
+			return nil, []error{fmt.Errorf("%s: percentage of version %q must not be negative, got %d", k, version, weight)}
+		}
+		total += weight
+	}
+	if total != 100 {
+		return nil, []error{fmt.Errorf("%s: percentages must add up to 100, got %d", k, total)}
+	}
+	return nil, nil
+}
+
+func routeConfigAddr(config *Config, d *schema.ResourceData) string {
+	return urlJoin(config.host, "/v3/namespaces", d.Get("namespace").(string), "/apps", d.Get("app").(string), "/services", d.Get("service").(string), "/routeconfig")
+}
+
+func resourceRouteConfigPut(d *schema.ResourceData, m interface{}) error {
+	config := m.(*Config)
+	if err := setDefaultNamespace(d, config); err != nil {
+		return err
+	}
+	routes := d.Get("routes").(map[string]interface{})
+	if err := checkAppVersions(config, d.Get("namespace").(string), d.Get("app").(string), routes); err != nil {
+		return err
+	}
+
+	b, err := json.Marshal(routes)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPut, routeConfigAddr(config, d), bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	if _, err := httpCall(config.httpClient, req); err != nil {
+		return err
+	}
+
+	d.SetId(strings.Join([]string{d.Get("namespace").(string), d.Get("app").(string), d.Get("service").(string)}, "/"))
+	return nil
+}
+
+// checkAppVersions returns an error listing the versions that are not
+// deployed, since CDAP would route requests for them nowhere.
+func checkAppVersions(config *Config, namespace, app string, routes map[string]interface{}) error {
+	var deployed []string
+	err := getJSON(config, urlJoin(config.host, "/v3/namespaces", namespace, "/apps", app, "/versions"), &deployed)
+	if isNotFound(err) {
+		return fmt.Errorf("application %q not found in namespace %q", app, namespace)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to list versions of application %q: %v", app, err)
+	}
+
+	exists := make(map[string]bool)
+	for _, v := range deployed {
+		exists[v] = true
+	}
+	var missing []string
+	for v := range routes {
+		if !exists[v] {
+			missing = append(missing, v)
+		}
+	}
+	if len(missing) > 0 {
+		sort.Strings(missing)
+		return fmt.Errorf("versions %s of application %q are not deployed", strings.Join(missing, ", "), app)
+	}
+	return nil
+}
+
+func resourceRouteConfigRead(d *schema.ResourceData, m interface{}) error {
+	config := m.(*Config)
+
+	routes := make(map[string]int)
+	err := getJSON(config, routeConfigAddr(config, d), &routes)
+	if isNotFound(err) {
+		log.Printf("route config %q not found, removing from state", d.Id())
+		d.SetId("")
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	// CDAP returns an empty config once it is deleted.
+	if len(routes) == 0 {
+		log.Printf("route config %q not found, removing from state", d.Id())
+		d.SetId("")
+		return nil
+	}
+
+	d.Set("routes", routes)
+	return nil
+}
+
+func resourceRouteConfigDelete(d *schema.ResourceData, m interface{}) error {
+	config := m.(*Config)
+	req, err := http.NewRequest(http.MethodDelete, routeConfigAddr(config, d), nil)
+	if err != nil {
+		return err
+	}
+	_, err = httpCall(config.httpClient, req)
+	if isNotFound(err) {
+		// The application was deleted along with its route config.
+		return nil
+	}
+	return err
+}
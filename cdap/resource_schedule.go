@@ -0,0 +1,351 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cdap
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/structure"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+)
+
+// https://docs.cdap.io/cdap/current/en/reference-manual/http-restful-api/lifecycle.html#schedules
+func resourceSchedule() *schema.Resource {
+	normalizeJSON := func(v interface{}) string {
+		json, _ := structure.NormalizeJsonString(v)
+		return json
+	}
+
+	return &schema.Resource{
+		Create: resourceScheduleCreate,
+		Read:   resourceScheduleRead,
+		Update: resourceScheduleUpdate,
+		Delete: resourceScheduleDelete,
+		Exists: resourceScheduleExists,
+
+		Schema: map[string]*schema.Schema{
+			"name": {
+				Type:        schema.TypeString,
+				Required:    true,
+				ForceNew:    true,
+				Description: "The name of the schedule.",
+			},
+			"namespace": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Computed:    true,
+				ForceNew:    true,
+				Description: "The name of the namespace in which this resource belongs. If not provided, the provider's default_namespace is used.",
+			},
+			"app": {
+				Type:        schema.TypeString,
+				Required:    true,
+				ForceNew:    true,
+				Description: "The name of the application the schedule belongs to.",
+			},
+			"description": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Description: "A description of the schedule.",
+			},
+			"program_type": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				Default:      "workflow",
+				ValidateFunc: validation.StringInSlice([]string{"workflow"}, true),
+				Description:  "The type of the program to run. CDAP only supports scheduling workflows.",
+			},
+			"program_name": {
+				Type:        schema.TypeString,
+				Required:    true,
+				Description: "The name of the program to run.",
+			},
+			"cron": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				ExactlyOneOf: []string{"cron", "trigger"},
+				Description:  "The cron expression of a time based schedule.",
+			},
+			"trigger": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				ValidateFunc: validation.StringIsJSON,
+				StateFunc:    normalizeJSON,
+				Description:  "The trigger of the schedule as JSON, for schedules that are not time based such as program status or partition triggers.",
+			},
+			"constraints": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				ValidateFunc: validation.StringIsJSON,
+				StateFunc:    normalizeJSON,
+				Description:  "The run constraints of the schedule as a JSON array, such as concurrency or delay constraints.",
+			},
+			"properties": {
+				Type:        schema.TypeMap,
+				Optional:    true,
+				Description: "The properties passed to the program as runtime arguments.",
+				Elem: &schema.Schema{
+					Type: schema.TypeString,
+				},
+			},
+			"timeout_millis": {
+				Type:        schema.TypeInt,
+				Optional:    true,
+				Computed:    true,
+				Description: "How long a triggered run waits for its constraints to be met before it is abandoned.",
+			},
+			"enabled": {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Default:     true,
+				Description: "Whether the schedule is enabled. CDAP creates schedules suspended, so they are enabled after creation unless this is false.",
+			},
+			"status": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "The status of the schedule as reported by CDAP, either SCHEDULED or SUSPENDED.",
+			},
+		},
+	}
+}
+
+type scheduleDetail struct {
+	Name          string            `json:"name,omitempty"`
+	Description   string            `json:"description"`
+	Program       *scheduleProgram  `json:"program"`
+	Properties    map[string]string `json:"properties"`
+	Trigger       json.RawMessage   `json:"trigger"`
+	Constraints   json.RawMessage   `json:"constraints,omitempty"`
+	TimeoutMillis int64             `json:"timeoutMillis,omitempty"`
+}
+
+type scheduleProgram struct {
+	ProgramName string `json:"programName"`
+	ProgramType string `json:"programType"`
+}
+
+type timeTrigger struct {
+	Type           string `json:"type"`
+	CronExpression string `json:"cronExpression"`
+}
+
+func scheduleAddr(config *Config, d *schema.ResourceData) string {
+	return urlJoin(config.host, "/v3/namespaces", d.Get("namespace").(string), "/apps", d.Get("app").(string), "/schedules", d.Get("name").(string))
+}
+
+func scheduleFromResource(d *schema.ResourceData) (*scheduleDetail, error) {
+	s := &scheduleDetail{
+		Description: d.Get("description").(string),
+		Program: &scheduleProgram{
+			ProgramName: d.Get("program_name").(string),
+			ProgramType: strings.ToUpper(d.Get("program_type").(string)),
+		},
+		Properties:    make(map[string]string),
+		TimeoutMillis: int64(d.Get("timeout_millis").(int)),
+	}
+	for k, v := range d.Get("properties").(map[string]interface{}) {
+		s.Properties[k] = v.(string)
+	}
+
+	if cron, ok := d.GetOk("cron"); ok {
+		b, err := json.Marshal(&timeTrigger{Type: "TIME", CronExpression: cron.(string)})
+		if err != nil {
+			return nil, err
+		}
+		s.Trigger = b
+	} else {
+		s.Trigger = json.RawMessage(d.Get("trigger").(string))
+	}
+	if c, ok := d.GetOk("constraints"); ok {
+		s.Constraints = json.RawMessage(c.(string))
+	}
+	return s, nil
+}
+
+func resourceScheduleCreate(d *schema.ResourceData, m interface{}) error {
+	config := m.(*Config)
+	if err := setDefaultNamespace(d, config); err != nil {
+		return err
+	}
+	namespace, app := d.Get("namespace").(string), d.Get("app").(string)
+
+	// CDAP's error for a missing app does not say which part of the path is missing.
+	req, err := http.NewRequest(http.MethodGet, urlJoin(config.host, "/v3/namespaces", namespace, "/apps", app), nil)
+	if err != nil {
+		return err
+	}
+	if _, err := httpCall(config.httpClient, req); isNotFound(err) {
+		return fmt.Errorf("cannot create schedule for application %q in namespace %q, the application does not exist", app, namespace)
+	} else if err != nil {
+		return err
+	}
+
+	if err := putSchedule(config, d, http.MethodPut, scheduleAddr(config, d)); err != nil {
+		return err
+	}
+	d.SetId(d.Get("name").(string))
+
+	if d.Get("enabled").(bool) {
+		return setScheduleEnabled(config, d, true)
+	}
+	return nil
+}
+
+func putSchedule(config *Config, d *schema.ResourceData, method, addr string) error {
+	s, err := scheduleFromResource(d)
+	if err != nil {
+		return err
+	}
+	b, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(method, addr, bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	_, err = httpCall(config.httpClient, req)
+	return err
+}
+
+func setScheduleEnabled(config *Config, d *schema.ResourceData, enabled bool) error {
+	action := "/disable"
+	if enabled {
+		action = "/enable"
+	}
+	req, err := http.NewRequest(http.MethodPost, urlJoin(scheduleAddr(config, d), action), nil)
+	if err != nil {
+		return err
+	}
+	_, err = httpCall(config.httpClient, req)
+	return err
+}
+
+func resourceScheduleRead(d *schema.ResourceData, m interface{}) error {
+	config := m.(*Config)
+	addr := scheduleAddr(config, d)
+
+	req, err := http.NewRequest(http.MethodGet, addr, nil)
+	if err != nil {
+		return err
+	}
+	b, err := httpCall(config.httpClient, req)
+	if isNotFound(err) {
+		log.Printf("schedule %q not found, removing from state", d.Id())
+		d.SetId("")
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	s := new(scheduleDetail)
+	if err := json.Unmarshal(b, s); err != nil {
+		return err
+	}
+
+	d.Set("description", s.Description)
+	d.Set("properties", s.Properties)
+	d.Set("timeout_millis", s.TimeoutMillis)
+	if s.Program != nil {
+		d.Set("program_name", s.Program.ProgramName)
+		d.Set("program_type", strings.ToLower(s.Program.ProgramType))
+	}
+
+	var tt timeTrigger
+	if err := json.Unmarshal(s.Trigger, &tt); err == nil && tt.Type == "TIME" && d.Get("trigger").(string) == "" {
+		d.Set("cron", tt.CronExpression)
+	} else if trigger, err := structure.NormalizeJsonString(string(s.Trigger)); err == nil {
+		d.Set("trigger", trigger)
+	}
+	if _, ok := d.GetOk("constraints"); ok {
+		if constraints, err := structure.NormalizeJsonString(string(s.Constraints)); err == nil {
+			d.Set("constraints", constraints)
+		}
+	}
+
+	req, err = http.NewRequest(http.MethodGet, urlJoin(addr, "/status"), nil)
+	if err != nil {
+		return err
+	}
+	b, err = httpCall(config.httpClient, req)
+	if err != nil {
+		return err
+	}
+	var status struct {
+		Status string `json:"status"`
+	}
+	if err := json.Unmarshal(b, &status); err != nil {
+		return err
+	}
+	d.Set("status", status.Status)
+	d.Set("enabled", status.Status == "SCHEDULED")
+	return nil
+}
+
+func resourceScheduleUpdate(d *schema.ResourceData, m interface{}) error {
+	config := m.(*Config)
+	if d.HasChangeExcept("enabled") {
+		if err := putSchedule(config, d, http.MethodPost, urlJoin(scheduleAddr(config, d), "/update")); err != nil {
+			return err
+		}
+	}
+	// Updating a schedule suspends it, so always restore the desired state.
+	return setScheduleEnabled(config, d, d.Get("enabled").(bool))
+}
+
+func resourceScheduleDelete(d *schema.ResourceData, m interface{}) error {
+	config := m.(*Config)
+
+	// Suspend the schedule first so no new runs are started while it is deleted.
+	if err := setScheduleEnabled(config, d, false); err != nil && !isNotFound(err) {
+		return fmt.Errorf("failed to suspend schedule %q before deletion: %v", d.Id(), err)
+	}
+
+	req, err := http.NewRequest(http.MethodDelete, scheduleAddr(config, d), nil)
+	if err != nil {
+		return err
+	}
+	_, err = httpCall(config.httpClient, req)
+	return err
+}
+
+func resourceScheduleExists(d *schema.ResourceData, m interface{}) (bool, error) {
+	config := m.(*Config)
+
+	namespace := d.Get("namespace").(string)
+	if exists, err := namespaceExists(config, namespace); err != nil {
+		return false, fmt.Errorf("failed to check for existence of namespace %q: %v", namespace, err)
+	} else if !exists {
+		return false, nil
+	}
+
+	req, err := http.NewRequest(http.MethodGet, scheduleAddr(config, d), nil)
+	if err != nil {
+		return false, err
+	}
+	_, err = httpCall(config.httpClient, req)
+	if isNotFound(err) {
+		return false, nil
+	}
+	return err == nil, err
+}
@@ -0,0 +1,195 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cdap
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+// https://docs.cdap.io/cdap/current/en/reference-manual/http-restful-api/secure-storage.html
+func resourceSecureKey() *schema.Resource {
+	return &schema.Resource{
+		Create: resourceSecureKeyPut,
+		Read:   resourceSecureKeyRead,
+		Update: resourceSecureKeyPut,
+		Delete: resourceSecureKeyDelete,
+		Exists: resourceSecureKeyExists,
+
+		Schema: map[string]*schema.Schema{
+			"name": {
+				Type:        schema.TypeString,
+				Required:    true,
+				ForceNew:    true,
+				Description: "The name of the secure key. Pipelines reference it as ${secure(name)}.",
+			},
+			"namespace": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Computed:    true,
+				ForceNew:    true,
+				Description: "The name of the namespace in which this resource belongs. If not provided, the provider's default_namespace is used.",
+			},
+			"data": {
+				Type:        schema.TypeString,
+				Required:    true,
+				Sensitive:   true,
+				Description: "The secret value. CDAP never returns it, so changes made outside of Terraform are not detected.",
+			},
+			"description": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Description: "A description of the secure key.",
+			},
+			"properties": {
+				Type:        schema.TypeMap,
+				Optional:    true,
+				Description: "The properties of the secure key.",
+				Elem: &schema.Schema{
+					Type: schema.TypeString,
+				},
+			},
+		},
+	}
+}
+
+type secureKey struct {
+	Description string            `json:"description"`
+	Data        string            `json:"data,omitempty"`
+	Properties  map[string]string `json:"properties"`
+}
+
+// resourceSecureKeyPut creates or overwrites the secure key, since CDAP uses
+// the same call for both.
+func resourceSecureKeyPut(d *schema.ResourceData, m interface{}) error {
+	config := m.(*Config)
+	if err := setDefaultNamespace(d, config); err != nil {
+		return err
+	}
+	name := d.Get("name").(string)
+	addr := urlJoin(config.host, "/v3/namespaces", d.Get("namespace").(string), "/securekeys", name)
+
+	key := &secureKey{
+		Description: d.Get("description").(string),
+		Data:        d.Get("data").(string),
+		Properties:  make(map[string]string),
+	}
+	for k, v := range d.Get("properties").(map[string]interface{}) {
+		key.Properties[k] = v.(string)
+	}
+
+	b, err := json.Marshal(key)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPut, addr, bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	if _, err := httpCall(config.httpClient, req); err != nil {
+		return err
+	}
+
+	d.SetId(name)
+	return nil
+}
+
+func resourceSecureKeyRead(d *schema.ResourceData, m interface{}) error {
+	config := m.(*Config)
+	name := d.Get("name").(string)
+	// Only read the metadata, the data is never stored from CDAP's response.
+	addr := urlJoin(config.host, "/v3/namespaces", d.Get("namespace").(string), "/securekeys", name, "/metadata")
+
+	req, err := http.NewRequest(http.MethodGet, addr, nil)
+	if err != nil {
+		return err
+	}
+
+	b, err := httpCall(config.httpClient, req)
+	if isNotFound(err) {
+		log.Printf("secure key %q not found, removing from state", name)
+		d.SetId("")
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	key := new(secureKey)
+	if err := json.Unmarshal(b, key); err != nil {
+		return err
+	}
+
+	d.Set("description", key.Description)
+	d.Set("properties", key.Properties)
+	return nil
+}
+
+func resourceSecureKeyDelete(d *schema.ResourceData, m interface{}) error {
+	config := m.(*Config)
+	addr := urlJoin(config.host, "/v3/namespaces", d.Get("namespace").(string), "/securekeys", d.Get("name").(string))
+
+	req, err := http.NewRequest(http.MethodDelete, addr, nil)
+	if err != nil {
+		return err
+	}
+	_, err = httpCall(config.httpClient, req)
+	return err
+}
+
+func resourceSecureKeyExists(d *schema.ResourceData, m interface{}) (bool, error) {
+	config := m.(*Config)
+	name := d.Get("name").(string)
+
+	namespace := d.Get("namespace").(string)
+	if exists, err := namespaceExists(config, namespace); err != nil {
+		return false, fmt.Errorf("failed to check for existence of namespace %q: %v", namespace, err)
+	} else if !exists {
+		return false, nil
+	}
+
+	addr := urlJoin(config.host, "/v3/namespaces", namespace, "/securekeys")
+
+	req, err := http.NewRequest(http.MethodGet, addr, nil)
+	if err != nil {
+		return false, err
+	}
+
+	b, err := httpCall(config.httpClient, req)
+	if err != nil {
+		return false, err
+	}
+
+	type key struct {
+		Name string `json:"name"`
+	}
+
+	var keys []key
+	if err := json.Unmarshal(b, &keys); err != nil {
+		return false, err
+	}
+
+	for _, k := range keys {
+		if k.Name == name {
+			return true, nil
+		}
+	}
+	return false, nil
+}
@@ -0,0 +1,283 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cdap
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/structure"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+)
+
+// Streams were removed in CDAP 5.0, so this resource only works with older instances.
+// https://docs.cdap.io/cdap/4.3.4/en/reference-manual/http-restful-api/stream.html
+func resourceStream() *schema.Resource {
+	normalizeJSON := func(v interface{}) string {
+		json, _ := structure.NormalizeJsonString(v)
+		return json
+	}
+
+	return &schema.Resource{
+		Create: resourceStreamCreate,
+		Read:   resourceStreamRead,
+		Update: resourceStreamUpdate,
+		Delete: resourceStreamDelete,
+		Exists: resourceStreamExists,
+
+		Schema: map[string]*schema.Schema{
+			"name": {
+				Type:        schema.TypeString,
+				Required:    true,
+				ForceNew:    true,
+				Description: "The name of the stream.",
+			},
+			"namespace": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Computed:    true,
+				ForceNew:    true,
+				Description: "The name of the namespace in which this resource belongs. If not provided, the provider's default_namespace is used.",
+			},
+			"description": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Computed:    true,
+				Description: "A description of the stream.",
+			},
+			"ttl_seconds": {
+				Type:         schema.TypeInt,
+				Optional:     true,
+				Computed:     true,
+				ValidateFunc: validation.IntAtLeast(1),
+				Description:  "The number of seconds events are kept in the stream. If not provided, CDAP keeps them forever.",
+			},
+			"format": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Computed:    true,
+				Description: "The name of the format of the events, such as text, csv, tsv or avro.",
+			},
+			"schema": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				Computed:     true,
+				ValidateFunc: validation.StringIsJSON,
+				StateFunc:    normalizeJSON,
+				Description:  "The schema of the events as JSON.",
+			},
+			"format_settings": {
+				Type:        schema.TypeMap,
+				Optional:    true,
+				Description: "The settings of the format, such as the delimiter of csv events.",
+				Elem: &schema.Schema{
+					Type: schema.TypeString,
+				},
+			},
+			"notification_threshold_mb": {
+				Type:         schema.TypeInt,
+				Optional:     true,
+				Computed:     true,
+				ValidateFunc: validation.IntAtLeast(1),
+				Description:  "The amount of data in megabytes after which a notification is sent, for example to trigger data based schedules.",
+			},
+		},
+	}
+}
+
+// streamProperties are the properties of a stream, used to create and update it.
+type streamProperties struct {
+	TTL                   int           `json:"ttl,omitempty"`
+	Format                *streamFormat `json:"format,omitempty"`
+	NotificationThreshold int           `json:"notification.threshold.mb,omitempty"`
+	Description           string        `json:"description,omitempty"`
+}
+
+type streamFormat struct {
+	Name     string            `json:"name"`
+	Schema   json.RawMessage   `json:"schema,omitempty"`
+	Settings map[string]string `json:"settings,omitempty"`
+}
+
+func resourceStreamCreate(d *schema.ResourceData, m interface{}) error {
+	config := m.(*Config)
+	if err := setDefaultNamespace(d, config); err != nil {
+		return err
+	}
+	name := d.Get("name").(string)
+	addr := urlJoin(config.host, "/v3/namespaces", d.Get("namespace").(string), "/streams", name)
+
+	b, err := json.Marshal(getStreamProperties(d))
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPut, addr, bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	if _, err := httpCall(config.httpClient, req); err != nil {
+		if isNotFound(err) {
+			return fmt.Errorf("failed to create stream %q, the instance may not support streams, they were removed in CDAP 5.0: %v", name, err)
+		}
+		return err
+	}
+
+	d.SetId(name)
+	return resourceStreamRead(d, m)
+}
+
+func getStreamProperties(d *schema.ResourceData) *streamProperties {
+	props := &streamProperties{
+		TTL:                   d.Get("ttl_seconds").(int),
+		NotificationThreshold: d.Get("notification_threshold_mb").(int),
+		Description:           d.Get("description").(string),
+	}
+
+	format := d.Get("format").(string)
+	settings := make(map[string]string)
+	for k, v := range d.Get("format_settings").(map[string]interface{}) {
+		settings[k] = v.(string)
+	}
+	if s := d.Get("schema").(string); format != "" || s != "" || len(settings) > 0 {
+		props.Format = &streamFormat{
+			Name:     format,
+			Settings: settings,
+		}
+		if s != "" {
+			props.Format.Schema = json.RawMessage(s)
+		}
+	}
+	return props
+}
+
+func resourceStreamRead(d *schema.ResourceData, m interface{}) error {
+	config := m.(*Config)
+	name := d.Get("name").(string)
+	addr := urlJoin(config.host, "/v3/namespaces", d.Get("namespace").(string), "/streams", name)
+
+	req, err := http.NewRequest(http.MethodGet, addr, nil)
+	if err != nil {
+		return err
+	}
+
+	b, err := httpCall(config.httpClient, req)
+	if isNotFound(err) {
+		log.Printf("stream %q not found, removing from state", name)
+		d.SetId("")
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	props := new(streamProperties)
+	if err := json.Unmarshal(b, props); err != nil {
+		return err
+	}
+
+	d.Set("description", props.Description)
+	d.Set("ttl_seconds", props.TTL)
+	d.Set("notification_threshold_mb", props.NotificationThreshold)
+	if props.Format != nil {
+		d.Set("format", props.Format.Name)
+		if len(props.Format.Schema) > 0 {
+			s, err := structure.NormalizeJsonString(string(props.Format.Schema))
+			if err != nil {
+				return err
+			}
+			d.Set("schema", s)
+		}
+		// Formats add default settings, so only report the configured ones.
+		settings := make(map[string]string)
+		for k := range d.Get("format_settings").(map[string]interface{}) {
+			if v, ok := props.Format.Settings[k]; ok {
+				settings[k] = v
+			}
+		}
+		d.Set("format_settings", settings)
+	}
+	return nil
+}
+
+func resourceStreamUpdate(d *schema.ResourceData, m interface{}) error {
+	config := m.(*Config)
+	name := d.Get("name").(string)
+	addr := urlJoin(config.host, "/v3/namespaces", d.Get("namespace").(string), "/streams", name, "/properties")
+
+	b, err := json.Marshal(getStreamProperties(d))
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPut, addr, bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	_, err = httpCall(config.httpClient, req)
+
+	// CDAP rejects changes it can't apply to existing events, such as some schema changes.
+	var httpErr *httpError
+	if errors.As(err, &httpErr) && httpErr.code == http.StatusBadRequest {
+		return fmt.Errorf("failed to update stream %q, recreate it to apply this change: %v", name, err)
+	}
+	if err != nil {
+		return err
+	}
+	return resourceStreamRead(d, m)
+}
+
+func resourceStreamDelete(d *schema.ResourceData, m interface{}) error {
+	config := m.(*Config)
+	addr := urlJoin(config.host, "/v3/namespaces", d.Get("namespace").(string), "/streams", d.Get("name").(string))
+
+	req, err := http.NewRequest(http.MethodDelete, addr, nil)
+	if err != nil {
+		return err
+	}
+	_, err = httpCall(config.httpClient, req)
+	return err
+}
+
+func resourceStreamExists(d *schema.ResourceData, m interface{}) (bool, error) {
+	config := m.(*Config)
+
+	namespace := d.Get("namespace").(string)
+	if exists, err := namespaceExists(config, namespace); err != nil {
+		return false, fmt.Errorf("failed to check for existence of namespace %q: %v", namespace, err)
+	} else if !exists {
+		return false, nil
+	}
+
+	addr := urlJoin(config.host, "/v3/namespaces", namespace, "/streams", d.Get("name").(string))
+
+	req, err := http.NewRequest(http.MethodGet, addr, nil)
+	if err != nil {
+		return false, err
+	}
+
+	// Instances without streams also return 404, so the stream is gone either way.
+	if _, err := httpCall(config.httpClient, req); isNotFound(err) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	return true, nil
+}
@@ -52,11 +52,9 @@ func resourceStreamingProgramRun() *schema.Resource {
 			"namespace": {
 				Type:        schema.TypeString,
 				Optional:    true,
+				Computed:    true,
 				ForceNew:    true,
-				Description: "The name of the namespace in which this resource belongs. If not provided, the default namespace is used.",
-				DefaultFunc: func() (interface{}, error) {
-					return defaultNamespace, nil
-				},
+				Description: "The name of the namespace in which this resource belongs. If not provided, the provider's default_namespace is used.",
 			},
 			"app": {
 				Type:        schema.TypeString,
@@ -107,6 +105,9 @@ func resourceStreamingProgramRun() *schema.Resource {
 
 func resourceStreamingProgramRunCreate(d *schema.ResourceData, m interface{}) error {
 	config := m.(*Config)
+	if err := setDefaultNamespace(d, config); err != nil {
+		return err
+	}
 
 	addr := getProgramAddr(config, d)
 	startAddr := urlJoin(addr, "start")
@@ -0,0 +1,83 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cdap
+
+import (
+	"net/http"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+// resourceSystemArtifactLoad reloads the system artifacts from the artifact
+// directories of the instance, such as after new plugin JARs were placed
+// there during an upgrade. Like a null_resource, it does nothing on delete
+// and is re-run when its triggers change.
+// https://docs.cdap.io/cdap/current/en/reference-manual/http-restful-api/artifact.html
+func resourceSystemArtifactLoad() *schema.Resource {
+	return &schema.Resource{
+		Create: resourceSystemArtifactLoadCreate,
+		Read:   resourceSystemArtifactLoadRead,
+		Delete: resourceSystemArtifactLoadDelete,
+
+		Schema: map[string]*schema.Schema{
+			"triggers": {
+				Type:        schema.TypeMap,
+				Optional:    true,
+				ForceNew:    true,
+				Description: "Arbitrary values that reload the system artifacts when changed, such as checksums of the JARs.",
+				Elem: &schema.Schema{
+					Type: schema.TypeString,
+				},
+			},
+			"artifact_count": {
+				Type:        schema.TypeInt,
+				Computed:    true,
+				Description: "The number of system artifacts after the load.",
+			},
+		},
+	}
+}
+
+func resourceSystemArtifactLoadCreate(d *schema.ResourceData, m interface{}) error {
+	config := m.(*Config)
+	addr := urlJoin(config.host, "/v3/namespaces", systemNamespace, "/artifacts")
+
+	req, err := http.NewRequest(http.MethodPost, addr, nil)
+	if err != nil {
+		return err
+	}
+	if _, err := httpCall(config.httpClient, req); err != nil {
+		return err
+	}
+
+	summaries, err := listArtifacts(config, systemNamespace, "system")
+	if err != nil {
+		return err
+	}
+
+	d.Set("artifact_count", len(summaries))
+	d.SetId(resource.UniqueId())
+	return nil
+}
+
+func resourceSystemArtifactLoadRead(d *schema.ResourceData, m interface{}) error {
+	return nil
+}
+
+func resourceSystemArtifactLoadDelete(d *schema.ResourceData, m interface{}) error {
+	// Loaded artifacts are not deleted, since they are managed by the artifact directories.
+	return nil
+}
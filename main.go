@@ -22,7 +22,11 @@ import (
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
 )
 
+// version is set at build time by goreleaser.
+var version = "dev"
+
 func main() {
+	cdap.Version = version
 	plugin.Serve(&plugin.ServeOpts{
 		ProviderFunc: func() *schema.Provider {
 			return cdap.Provider()
@@ -47,18 +47,27 @@ func generate(provider *schema.Provider, tmplDir, outputDir string) error {
 		return err
 	}
 
-	if len(provider.ResourcesMap) == 0 {
+	if err := generateResources(provider.ResourcesMap, tmplDir, outputDir, "resources"); err != nil {
+		return err
+	}
+	return generateResources(provider.DataSourcesMap, tmplDir, outputDir, "data-sources")
+}
+
+// generateResources writes the docs for resources or data sources to the
+// given subdirectory of the output directory.
+func generateResources(resources map[string]*schema.Resource, tmplDir, outputDir, subDir string) error {
+	if len(resources) == 0 {
 		return nil
 	}
 
-	resourcesOutputDir := filepath.Join(outputDir, "resources")
+	resourcesOutputDir := filepath.Join(outputDir, subDir)
 	if err := os.MkdirAll(resourcesOutputDir, 0755); err != nil {
 		return err
 	}
 
-	for name, res := range provider.ResourcesMap {
+	for name, res := range resources {
 		tmplName := fmt.Sprintf("%s.md.tmpl", name)
-		tmpl, err := template.New(tmplName).ParseFiles(templateFiles(tmplDir, "resources/"+tmplName)...)
+		tmpl, err := template.New(tmplName).ParseFiles(templateFiles(tmplDir, subDir+"/"+tmplName)...)
 		if err != nil {
 			return err
 		}